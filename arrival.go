@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ArrivalConstant = "constant"
+	ArrivalPoisson  = "poisson"
+)
+
+var rateUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// Rate is a number of iterations started per period
+type Rate struct {
+	Iterations float64
+	Period     time.Duration
+}
+
+// ParseRate parses rates like 200/s, 30/m or 5/10s
+func ParseRate(value string) (Rate, error) {
+	parts := strings.SplitN(value, "/", 2)
+
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("Rate must be like 200/s, not %q", value)
+	}
+
+	iterations, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+
+	if err != nil || iterations <= 0 {
+		return Rate{}, fmt.Errorf("Rate must start with a positive number, not %q", value)
+	}
+
+	unit := strings.TrimSpace(parts[1])
+	period, ok := rateUnits[unit]
+
+	if !ok {
+		period, err = time.ParseDuration(unit)
+
+		if err != nil || period <= 0 {
+			return Rate{}, fmt.Errorf("Rate must be per s, m, h or a duration, not %q", value)
+		}
+	}
+
+	return Rate{Iterations: iterations, Period: period}, nil
+}
+
+func (r Rate) PerSecond() float64 {
+	return r.Iterations / r.Period.Seconds()
+}
+
+// Interval returns the time until the next iteration, which is exponentially
+// distributed for poisson arrivals
+func (r Rate) Interval(arrival string) time.Duration {
+	interval := float64(r.Period) / r.Iterations
+
+	if arrival == ArrivalPoisson {
+		interval *= rand.ExpFloat64()
+	}
+
+	return time.Duration(interval)
+}
+
+// RunArrivals starts iterations of a scenario at its rate, no matter how
+// long they take, from a pool of as many workers as its concurrency.
+// Iterations are dropped when every worker is busy. No iterations are
+// started once stop is closed.
+func RunArrivals(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	name string,
+	status *Status,
+	stop <-chan struct{},
+) {
+	logger := logrus.WithField("scenario", name)
+	config, _ := targets.Config()
+	scenario := config.Scenario(name)
+
+	if scenario == nil {
+		return
+	}
+
+	idle := make(chan *Worker, scenario.Concurrency)
+
+	for i := 0; i < scenario.Concurrency; i++ {
+		idle <- NewWorker(targets, name, status, run.Results)
+	}
+
+	var rate Rate
+
+	started := 0
+	version := 0
+	next := time.Now()
+
+loop:
+	for {
+		config, current := targets.Config()
+
+		if current != version {
+			scenario = config.Scenario(name)
+
+			if scenario == nil {
+				logger.Warn("Scenario was removed from targets. Closing down.")
+				break
+			}
+
+			var err error
+			rate, err = ParseRate(scenario.Rate)
+
+			if err != nil {
+				logger.WithError(err).Warn("Scenario has no valid rate. Closing down.")
+				break
+			}
+
+			IterationsTargetRateGauge.WithLabelValues(name).Set(rate.PerSecond())
+			version = current
+		}
+
+		if scenario.Repeat > -1 && started > scenario.Repeat {
+			logger.Info("Number of repeats reached. Closing down.")
+			break
+		}
+
+		// Start over from now, instead of catching up in a burst, when
+		// falling more than a period behind
+		if time.Since(next) > rate.Period {
+			next = time.Now()
+		}
+
+		next = next.Add(rate.Interval(scenario.Arrival))
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-run.Stopping():
+			break loop
+		case <-stop:
+			break loop
+		case <-time.After(time.Until(next)):
+		}
+
+		select {
+		case worker := <-idle:
+			started++
+
+			run.Go(func() {
+				if arrive(ctx, run, worker) {
+					idle <- worker
+				}
+			})
+		default:
+			IterationsDroppedCounter.WithLabelValues(name).Inc()
+			logger.Warn("Every worker is busy. Dropping iteration.")
+		}
+	}
+}
+
+// arrive runs a single iteration of a worker started by RunArrivals. It
+// returns whether the worker can be used again.
+func arrive(ctx context.Context, run *Run, worker *Worker) bool {
+	if !worker.Update() {
+		worker.Logger().Warn("Scenario was removed from targets. Stopping worker.")
+		return false
+	}
+
+	ActiveVirtualUsersGauge.WithLabelValues(worker.Name).Inc()
+	defer ActiveVirtualUsersGauge.WithLabelValues(worker.Name).Dec()
+
+	runLogger := worker.Logger()
+	runLogger.Info("Initiated requests")
+
+	err := worker.Iterate(ctx)
+	worker.Repeated++
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, ErrStopRun) {
+		runLogger.WithError(err).Warn("Stopping run. Closing down.")
+		run.Stop()
+		return false
+	}
+
+	if errors.Is(err, ErrStopWorker) {
+		runLogger.WithError(err).Warn("Stopping worker.")
+		return false
+	}
+
+	return true
+}
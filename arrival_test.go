@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	for value, expected := range map[string]float64{
+		"200/s":  200,
+		"30/m":   0.5,
+		"5/10s":  0.5,
+		"1.5/1s": 1.5,
+	} {
+		rate, err := ParseRate(value)
+
+		if err != nil {
+			t.Errorf("Could not parse rate %s: %s", value, err)
+			continue
+		}
+
+		if rate.PerSecond() != expected {
+			t.Errorf("Rate %s is %f per second instead of %f", value, rate.PerSecond(), expected)
+		}
+	}
+
+	for _, value := range []string{"200", "0/s", "-1/s", "10/week", "ten/s"} {
+		if _, err := ParseRate(value); err == nil {
+			t.Errorf("Rate %s should not be valid", value)
+		}
+	}
+}
+
+func TestRateInterval(t *testing.T) {
+	rate := Rate{Iterations: 100, Period: time.Second}
+
+	if rate.Interval(ArrivalConstant) != 10*time.Millisecond {
+		t.Errorf("Constant interval is %s", rate.Interval(ArrivalConstant))
+	}
+
+	var total time.Duration
+
+	for i := 0; i < 10000; i++ {
+		total += rate.Interval(ArrivalPoisson)
+	}
+
+	mean := total / 10000
+
+	if mean < 9*time.Millisecond || mean > 11*time.Millisecond {
+		t.Errorf("Mean poisson interval is %s", mean)
+	}
+}
+
+func TestRunArrivals(t *testing.T) {
+	var called int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&called, 1)
+	}))
+	defer server.Close()
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{{
+		Name:        "arrivals",
+		Concurrency: 2,
+		Repeat:      9,
+		Rate:        "100/s",
+		Requests:    []*Request{{Name: "index", Method: "GET", URL: server.URL}},
+	}}})
+
+	run := NewRun()
+	then := time.Now()
+
+	run.Go(func() { RunArrivals(context.Background(), run, targets, "arrivals", NewStatus(), nil) })
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
+
+	if atomic.LoadInt32(&called) != 10 {
+		t.Errorf("Started %d iterations instead of 10", called)
+	}
+
+	if time.Since(then) < 90*time.Millisecond {
+		t.Errorf("Iterations were started faster than the rate: %s", time.Since(then))
+	}
+}
+
+func TestRunArrivalsDropped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{{
+		Name:        "dropped",
+		Concurrency: 1,
+		Repeat:      -1,
+		Rate:        "100/s",
+		Requests:    []*Request{{Name: "slow", Method: "GET", URL: server.URL}},
+	}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	run := NewRun()
+	run.Go(func() { RunArrivals(ctx, run, targets, "dropped", NewStatus(), nil) })
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
+
+	if counterValue(IterationsDroppedCounter.WithLabelValues("dropped")) < 10 {
+		t.Error("Iterations were not dropped while the worker was busy")
+	}
+
+	if gaugeValue(IterationsTargetRateGauge.WithLabelValues("dropped")) != 100 {
+		t.Error("Target rate was not set")
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+const DefaultMaxRedirects = 10
+
+type ClientConfig struct {
+	CaFile              string `yaml:"ca_file"`
+	CertFile            string `yaml:"cert_file"`
+	KeyFile             string `yaml:"key_file"`
+	InsecureSkipVerify  *bool  `yaml:"insecure_skip_verify"`
+	Proxy               string `yaml:"proxy"`
+	MaxIdleConnsPerHost int    `yaml:"max_idle_conns_per_host"`
+	KeepAlive           *bool  `yaml:"keep_alive"`
+	HTTPVersion         string `yaml:"http_version"`
+	FollowRedirects     *bool  `yaml:"follow_redirects"`
+	MaxRedirects        int    `yaml:"max_redirects"`
+}
+
+// Merge returns a copy of the client config with every field set in
+// override replacing its own.
+func (c *ClientConfig) Merge(override *ClientConfig) *ClientConfig {
+	if c == nil {
+		return override
+	}
+
+	merged := *c
+
+	if override == nil {
+		return &merged
+	}
+
+	if override.CaFile != "" {
+		merged.CaFile = override.CaFile
+	}
+
+	if override.CertFile != "" {
+		merged.CertFile = override.CertFile
+		merged.KeyFile = override.KeyFile
+	}
+
+	if override.InsecureSkipVerify != nil {
+		merged.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+
+	if override.MaxIdleConnsPerHost != 0 {
+		merged.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+
+	if override.KeepAlive != nil {
+		merged.KeepAlive = override.KeepAlive
+	}
+
+	if override.HTTPVersion != "" {
+		merged.HTTPVersion = override.HTTPVersion
+	}
+
+	if override.FollowRedirects != nil {
+		merged.FollowRedirects = override.FollowRedirects
+	}
+
+	if override.MaxRedirects != 0 {
+		merged.MaxRedirects = override.MaxRedirects
+	}
+
+	return &merged
+}
+
+func (c *ClientConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("Client cert_file and key_file must be set together")
+	}
+
+	switch c.HTTPVersion {
+	case "", "1.1", "2":
+	default:
+		return fmt.Errorf("Client http_version must be 1.1 or 2, not %q", c.HTTPVersion)
+	}
+
+	if c.Proxy != "" {
+		proxy, err := url.Parse(c.Proxy)
+
+		if err != nil {
+			return fmt.Errorf("Client proxy: %s", err)
+		}
+
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("Client proxy must be http, https or socks5, not %q", proxy.Scheme)
+		}
+	}
+
+	if c.MaxIdleConnsPerHost < 0 {
+		return errors.New("Client max_idle_conns_per_host can't be negative")
+	}
+
+	if c.MaxRedirects < 0 {
+		return errors.New("Client max_redirects can't be negative")
+	}
+
+	return nil
+}
+
+// Build creates an http client from the config. Relative file paths are
+// resolved from dir, which is the directory of the targets file.
+func (c *ClientConfig) Build(dir string) (*http.Client, error) {
+	err := c.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if c.InsecureSkipVerify != nil {
+		tlsConfig.InsecureSkipVerify = *c.InsecureSkipVerify
+	}
+
+	if c.CaFile != "" {
+		pem, err := ioutil.ReadFile(resolvePath(dir, c.CaFile))
+
+		if err != nil {
+			return nil, fmt.Errorf("Client ca_file: %s", err)
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Client ca_file %s contains no certificates", c.CaFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(
+			resolvePath(dir, c.CertFile),
+			resolvePath(dir, c.KeyFile),
+		)
+
+		if err != nil {
+			return nil, fmt.Errorf("Client cert_file/key_file: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	if c.Proxy != "" {
+		proxy, _ := url.Parse(c.Proxy)
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if c.KeepAlive != nil {
+		transport.DisableKeepAlives = !*c.KeepAlive
+	}
+
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: c.checkRedirect,
+	}
+
+	switch c.HTTPVersion {
+	case "1.1":
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	case "2":
+		client.Transport = &http2Transport{transport}
+	}
+
+	return client, nil
+}
+
+// http2Transport fails requests that weren't sent over HTTP/2. The
+// transport offers both HTTP/2 and HTTP/1.1 when connecting, and uses
+// whichever the server picks.
+type http2Transport struct {
+	*http.Transport
+}
+
+func (t *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Transport.RoundTrip(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.ProtoMajor != 2 {
+		res.Body.Close()
+		return nil, fmt.Errorf("Server responded with %s, not HTTP/2", res.Proto)
+	}
+
+	return res, nil
+}
+
+func (c *ClientConfig) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.FollowRedirects != nil && !*c.FollowRedirects {
+		return http.ErrUseLastResponse
+	}
+
+	max := c.MaxRedirects
+
+	if max == 0 {
+		max = DefaultMaxRedirects
+	}
+
+	if len(via) >= max {
+		return fmt.Errorf("Stopped after %d redirects", max)
+	}
+
+	return nil
+}
+
+func resolvePath(dir, filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(dir, filename)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClientConfigMerge(t *testing.T) {
+	yes := true
+	no := false
+
+	global := &ClientConfig{
+		CaFile:              "ca.pem",
+		InsecureSkipVerify:  &yes,
+		MaxIdleConnsPerHost: 5,
+	}
+
+	merged := global.Merge(&ClientConfig{
+		InsecureSkipVerify: &no,
+		Proxy:              "socks5://localhost:1080",
+	})
+
+	if merged.CaFile != "ca.pem" ||
+		*merged.InsecureSkipVerify != false ||
+		merged.Proxy != "socks5://localhost:1080" ||
+		merged.MaxIdleConnsPerHost != 5 {
+		t.Errorf("Merged client config does not match: %+v", merged)
+	}
+
+	if *global.InsecureSkipVerify != true || global.Proxy != "" {
+		t.Error("Merge changed the global client config")
+	}
+
+	var empty *ClientConfig
+
+	if empty.Merge(nil) != nil {
+		t.Error("Merging nil configs should return nil")
+	}
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	for _, c := range []ClientConfig{
+		{CertFile: "cert.pem"},
+		{HTTPVersion: "3"},
+		{Proxy: "ftp://some-host"},
+		{MaxRedirects: -1},
+	} {
+		if c.Validate() == nil {
+			t.Errorf("Client config should not be valid: %+v", c)
+		}
+	}
+}
+
+func TestClientConfigCaFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte("Hello!"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.pem"), ca, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := (&ClientConfig{CaFile: "ca.pem"}).Build(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+
+	if err != nil {
+		t.Fatalf("Request with custom ca failed: %s", err)
+	}
+
+	res.Body.Close()
+
+	client, err = (&ClientConfig{}).Build(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Request without custom ca should fail")
+	}
+}
+
+func TestClientConfigRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "/again", http.StatusFound)
+	}))
+	defer server.Close()
+
+	no := false
+	client, err := (&ClientConfig{FollowRedirects: &no}).Build("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Errorf("Redirect was followed: %d", res.StatusCode)
+	}
+
+	client, err = (&ClientConfig{MaxRedirects: 2}).Build("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Redirects should stop after max_redirects")
+	}
+}
+
+func TestClientConfigHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {})
+
+	http1 := httptest.NewTLSServer(handler)
+	defer http1.Close()
+
+	http2 := httptest.NewUnstartedServer(handler)
+	http2.EnableHTTP2 = true
+	http2.StartTLS()
+	defer http2.Close()
+
+	yes := true
+	client, err := (&ClientConfig{HTTPVersion: "2", InsecureSkipVerify: &yes}).Build("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(http2.URL)
+
+	if err != nil {
+		t.Fatalf("Request over HTTP/2 failed: %s", err)
+	}
+
+	res.Body.Close()
+
+	if res.ProtoMajor != 2 {
+		t.Errorf("Request was sent over %s, not HTTP/2", res.Proto)
+	}
+
+	if _, err := client.Get(http1.URL); err == nil {
+		t.Error("Request to a server without HTTP/2 should fail")
+	}
+}
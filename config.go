@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const DefaultScenarioName = "default"
+
+// ScenarioDefaults holds the values used for scenarios that don't set their
+// own. They're set from the command line flags.
+var ScenarioDefaults = Scenario{
+	Concurrency: 1,
+	Sleep:       1,
+	Repeat:      -1,
+	Session:     SessionIteration,
+}
+
+// DefaultTimeout is the request timeout used when the targets file doesn't
+// set one. It's set from the command line flags.
+var DefaultTimeout = 30 * time.Second
+
+type Config struct {
+	Timeout    time.Duration      `yaml:"timeout"`
+	Client     *ClientConfig      `yaml:"client"`
+	Scenarios  []*Scenario        `yaml:"scenarios"`
+	Feeders    map[string]*Feeder `yaml:"feeders"`
+	Thresholds []*Threshold       `yaml:"thresholds"`
+	Metrics    *MetricsConfig     `yaml:"metrics"`
+}
+
+// UnmarshalYAML accepts both a plain list of requests, which becomes the
+// default scenario, and a mapping with scenarios.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []interface{}
+
+	c.Timeout = DefaultTimeout
+
+	if unmarshal(&list) == nil {
+		var requests []*Request
+
+		err := unmarshal(&requests)
+
+		scenario := ScenarioDefaults
+		scenario.Name = DefaultScenarioName
+		scenario.Requests = requests
+
+		c.Scenarios = []*Scenario{&scenario}
+
+		return err
+	}
+
+	type plain Config
+
+	return unmarshal((*plain)(c))
+}
+
+func (c *Config) Scenario(name string) *Scenario {
+	for _, s := range c.Scenarios {
+		if s != nil && s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// Init connects every request with the scenario it belongs to, sets the
+// default timeout and think time and finds the feeders used by every scenario
+func (c *Config) Init() {
+	for name, f := range c.Feeders {
+		if f != nil {
+			f.Name = name
+		}
+	}
+
+	for _, s := range c.Scenarios {
+		if s == nil {
+			continue
+		}
+
+		var templates []string
+
+		for _, r := range s.Requests {
+			if r != nil {
+				r.Scenario = s.Name
+
+				if r.Timeout == 0 {
+					r.Timeout = c.Timeout
+				}
+
+				if r.ThinkTime == nil {
+					r.ThinkTime = s.ThinkTime
+				}
+
+				templates = append(templates, r.Templates()...)
+			}
+		}
+
+		s.Feeders = templateFeeders(templates)
+	}
+}
+
+// CloseClients closes the idle connections of the http clients of the
+// requests, once the config has been replaced
+func (c *Config) CloseClients() {
+	closed := make(map[*http.Client]bool)
+
+	for _, s := range c.Scenarios {
+		for _, r := range s.Requests {
+			if r.HTTPClient != nil && !closed[r.HTTPClient] {
+				closed[r.HTTPClient] = true
+				r.HTTPClient.CloseIdleConnections()
+			}
+		}
+	}
+}
+
+// LoadClients builds the http clients. Relative paths are resolved from
+// dir, which is the directory of the targets file.
+func (c *Config) LoadClients(dir string) error {
+	var global *http.Client
+
+	if c.Client != nil {
+		var err error
+		global, err = c.Client.Build(dir)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, s := range c.Scenarios {
+		for _, r := range s.Requests {
+			if r.Client == nil {
+				r.HTTPClient = global
+				continue
+			}
+
+			client, err := c.Client.Merge(r.Client).Build(dir)
+
+			if err != nil {
+				return fmt.Errorf("Request %q: %s", r.Name, err)
+			}
+
+			r.HTTPClient = client
+		}
+	}
+
+	return nil
+}
+
+type Scenario struct {
+	Name        string            `yaml:"name"`
+	Concurrency int               `yaml:"concurrency"`
+	Sleep       int               `yaml:"sleep"`
+	Repeat      int               `yaml:"repeat"`
+	Session     string            `yaml:"session"`
+	ThinkTime   *ThinkTime        `yaml:"think_time"`
+	Rate        string            `yaml:"rate"`
+	Arrival     string            `yaml:"arrival"`
+	Stages      []*Stage          `yaml:"stages"`
+	Labels      map[string]string `yaml:"labels"`
+	Requests    []*Request        `yaml:"requests"`
+	Feeders     []string          `yaml:"-"`
+}
+
+func (s *Scenario) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Scenario
+
+	*s = ScenarioDefaults
+
+	return unmarshal((*plain)(s))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLoadConfigWithScenarios(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: browse
+    concurrency: 3
+    labels:
+      team: shop
+    requests:
+      - name: start
+        url: http://some-host/
+  - name: checkout
+    repeat: 5
+    requests:
+      - name: pay
+        url: http://some-host/pay
+`)
+
+	tmpfile, err := ioutil.TempFile("", "*")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.Write(content)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := NewTargets(tmpfile.Name())
+
+	if err := targets.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := targets.Config()
+
+	browse := config.Scenario("browse")
+	checkout := config.Scenario("checkout")
+
+	if browse == nil || checkout == nil {
+		t.Fatal("Scenarios were not loaded")
+	}
+
+	if browse.Concurrency != 3 ||
+		browse.Sleep != ScenarioDefaults.Sleep ||
+		browse.Repeat != ScenarioDefaults.Repeat ||
+		browse.Labels["team"] != "shop" {
+		t.Errorf("Scenario browse does not match: %+v", browse)
+	}
+
+	if checkout.Concurrency != ScenarioDefaults.Concurrency || checkout.Repeat != 5 {
+		t.Errorf("Scenario checkout does not match: %+v", checkout)
+	}
+
+	if browse.Requests[0].Scenario != "browse" || checkout.Requests[0].Scenario != "checkout" {
+		t.Error("Requests were not connected to their scenarios")
+	}
+}
+
+func TestLoadConfigWithRequestList(t *testing.T) {
+	content := []byte(`
+- name: start
+  url: http://some-host/
+`)
+
+	tmpfile, err := ioutil.TempFile("", "*")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.Write(content)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := NewTargets(tmpfile.Name())
+
+	if err := targets.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := targets.Config()
+
+	scenario := config.Scenario(DefaultScenarioName)
+
+	if len(config.Scenarios) != 1 || scenario == nil || len(scenario.Requests) != 1 {
+		t.Fatal("Request list was not loaded as the default scenario")
+	}
+
+	if scenario.Requests[0].Scenario != DefaultScenarioName {
+		t.Error("Request was not connected to the default scenario")
+	}
+}
@@ -34,6 +34,22 @@ while true; do
       TARGETS=$2
       shift 2
       ;;
+    -timeout)
+      TIMEOUT=$2
+      shift 2
+      ;;
+    -watch)
+      WATCH=$2
+      shift 2
+      ;;
+    -duration)
+      DURATION=$2
+      shift 2
+      ;;
+    -grace)
+      GRACE=$2
+      shift 2
+      ;;
     *)
       break
       ;;
@@ -48,4 +64,8 @@ exec goload \
   -concurrency $CONCURRENCY \
   -sleep $SLEEP \
   -repeat $REPEAT \
-  -targets $TARGETS
+  -targets $TARGETS \
+  -timeout $TIMEOUT \
+  -watch $WATCH \
+  -duration $DURATION \
+  -grace $GRACE
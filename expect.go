@@ -1,81 +1,246 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
+// ExpectedBodyLength is how much of a body not matching body_re is included
+// in the failure
+const ExpectedBodyLength = 100
+
 type Expected struct {
-	Name       string
+	Scenario   string            `yaml:"-"`
+	Name       string            `yaml:"-"`
 	StatusCode string            `yaml:"status_code_re"`
 	Headers    map[string]string `yaml:"headers_re"`
 	Body       string            `yaml:"body_re"`
+	Cookies    map[string]string `yaml:"cookies_re"`
+	JSON       []*JSONAssertion  `yaml:"json"`
+	Schema     *JSONSchema       `yaml:"json_schema"`
+}
+
+// Defined tells whether there's anything to expect
+func (e *Expected) Defined() bool {
+	return e.StatusCode != "" || len(e.Headers) > 0 || e.Body != "" || len(e.Cookies) > 0 || len(e.JSON) > 0 || e.Schema != nil
+}
+
+// Check is the outcome of a single expectation on a response
+type Check struct {
+	Part     string `json:"part"`
+	Name     string `json:"name,omitempty"`
+	Expected string `json:"expected"`
+	Passed   bool   `json:"passed"`
+	Message  string `json:"message,omitempty"`
+}
+
+func (c *Check) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s %s", c.Part, c.Expected)
+	}
+
+	return fmt.Sprintf("%s %s %s", c.Part, c.Name, c.Expected)
+}
+
+// Parts returns the parts of the response there are expectations on
+func (e *Expected) Parts() []string {
+	var parts []string
+
+	if e.StatusCode != "" {
+		parts = append(parts, "status_code")
+	}
+
+	if len(e.Headers) > 0 {
+		parts = append(parts, "headers")
+	}
+
+	if e.Body != "" {
+		parts = append(parts, "body")
+	}
+
+	if len(e.Cookies) > 0 {
+		parts = append(parts, "cookies")
+	}
+
+	if len(e.JSON) > 0 {
+		parts = append(parts, "json")
+	}
+
+	if e.Schema != nil {
+		parts = append(parts, "schema")
+	}
+
+	return parts
 }
 
-func (e *Expected) Evaluate(name string, r *http.Response, b string) error {
+// Evaluate checks the response against every expectation, and returns the
+// outcome of each check and an error listing the ones that failed
+func (e *Expected) Evaluate(name string, r *http.Response, b string) ([]*Check, error) {
 	e.Name = name
 
-	e.EvaluateStatusCode(r.StatusCode)
-	e.EvaluateHeaders(&r.Header)
-	e.EvaluateBody(b)
+	var checks []*Check
 
-	return nil
+	if check := e.checkStatusCode(r.StatusCode); check != nil {
+		checks = append(checks, check)
+	}
+
+	checks = append(checks, e.checkHeaders(&r.Header)...)
+
+	if check := e.checkBody(b); check != nil {
+		checks = append(checks, check)
+	}
+
+	checks = append(checks, e.checkCookies(r.Cookies())...)
+	checks = append(checks, e.checkJSON(b)...)
+
+	if e.Schema != nil {
+		checks = append(checks, e.Schema.Check(b))
+	}
+
+	return checks, e.count(checks)
 }
 
 func (e *Expected) EvaluateStatusCode(s int) error {
-	counter := ExpectedResponseCounter.WithLabelValues(e.Name, "status_code")
+	return e.count(e.checks(e.checkStatusCode(s)))
+}
 
+func (e *Expected) EvaluateHeaders(h *http.Header) error {
+	return e.count(e.checkHeaders(h))
+}
+
+func (e *Expected) EvaluateBody(b string) error {
+	return e.count(e.checks(e.checkBody(b)))
+}
+
+func (e *Expected) EvaluateCookies(cookies []*http.Cookie) error {
+	return e.count(e.checkCookies(cookies))
+}
+
+func (e *Expected) checkStatusCode(s int) *Check {
 	if e.StatusCode == "" {
 		return nil
 	}
 
-	if match(e.StatusCode, fmt.Sprintf("%d", s)) {
-		counter.Inc()
-		return nil
+	check := &Check{Part: "status_code", Expected: e.StatusCode}
+	value := fmt.Sprintf("%d", s)
+
+	if check.Passed = match(e.StatusCode, value); !check.Passed {
+		check.Message = fmt.Sprintf("Status code %s, did not match %s", value, e.StatusCode)
 	}
 
-	return fmt.Errorf("Status code %d, did not match %s", s, e.StatusCode)
+	return check
 }
 
-func (e *Expected) EvaluateHeaders(h *http.Header) error {
-	counter := ExpectedResponseCounter.WithLabelValues(e.Name, "headers")
-	errors := 0
+func (e *Expected) checkHeaders(h *http.Header) []*Check {
+	var checks []*Check
+
+	for _, k := range sortedKeys(e.Headers) {
+		check := &Check{Part: "headers", Name: k, Expected: e.Headers[k]}
+		value := h.Get(k)
 
-	if len(e.Headers) == 0 {
+		if check.Passed = match(check.Expected, value); !check.Passed {
+			check.Message = fmt.Sprintf("Header %s %q, did not match %s", k, value, check.Expected)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func (e *Expected) checkBody(b string) *Check {
+	if e.Body == "" {
 		return nil
 	}
 
-	for k, v := range e.Headers {
-		if match(v, h.Get(k)) {
-			counter.Inc()
-		} else {
-			errors++
-		}
+	check := &Check{Part: "body", Expected: e.Body}
+
+	if check.Passed = match(e.Body, b); !check.Passed {
+		check.Message = fmt.Sprintf("Body %q, did not match %s", truncate(b, ExpectedBodyLength), e.Body)
 	}
 
-	if errors > 0 {
-		return fmt.Errorf("Headers did not match")
+	return check
+}
+
+func (e *Expected) checkCookies(cookies []*http.Cookie) []*Check {
+	var checks []*Check
+
+	values := make(map[string]string)
+
+	for _, c := range cookies {
+		values[c.Name] = c.Value
 	}
 
-	return nil
+	for _, k := range sortedKeys(e.Cookies) {
+		check := &Check{Part: "cookies", Name: k, Expected: e.Cookies[k]}
+		value, ok := values[k]
+
+		if !ok {
+			check.Message = fmt.Sprintf("Cookie %s was not set", k)
+		} else if check.Passed = match(check.Expected, value); !check.Passed {
+			check.Message = fmt.Sprintf("Cookie %s %q, did not match %s", k, value, check.Expected)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
 }
 
-func (e *Expected) EvaluateBody(b string) error {
-	counter := ExpectedResponseCounter.WithLabelValues(e.Name, "body")
+func (e *Expected) checkJSON(b string) []*Check {
+	var checks []*Check
 
-	if e.Body == "" {
-		return nil
+	for _, assertion := range e.JSON {
+		checks = append(checks, assertion.Check(b))
 	}
 
-	if match(e.Body, b) {
-		counter.Inc()
+	return checks
+}
+
+func (e *Expected) checks(check *Check) []*Check {
+	if check == nil {
 		return nil
 	}
 
-	return fmt.Errorf("Body did not match %s", e.Body)
+	return []*Check{check}
+}
+
+// count counts the checks by outcome, and returns an error listing the ones
+// that failed
+func (e *Expected) count(checks []*Check) error {
+	var failures []string
+
+	for _, check := range checks {
+		outcome := "passed"
+
+		if check.Passed {
+			ExpectedResponseCounter.WithLabelValues(e.Scenario, e.Name, check.Part).Inc()
+		} else {
+			outcome = "failed"
+			failures = append(failures, check.Message)
+		}
+
+		if check.Part == "schema" && !check.Passed {
+			ExpectedResponseCounter.WithLabelValues(e.Scenario, e.Name, "schema_failed").Inc()
+		}
+
+		ExpectationsCounter.WithLabelValues(e.Scenario, e.Name, check.Part, outcome).Inc()
+
+		if check.Part == "json" {
+			JSONAssertionsCounter.WithLabelValues(e.Scenario, e.Name, check.Name, outcome).Inc()
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, ", "))
+	}
+
+	return nil
 }
 
 func match(exp, target string) bool {
@@ -92,3 +257,11 @@ func match(exp, target string) bool {
 
 	return re.MatchString(target)
 }
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length] + "..."
+}
@@ -30,7 +30,7 @@ body_re: .*`)
 	}
 	b := ""
 
-	err = e.Evaluate("some name", &r, b)
+	checks, err := e.Evaluate("some name", &r, b)
 
 	if e.Name != "some name" {
 		t.Error("Name was not set")
@@ -39,6 +39,16 @@ body_re: .*`)
 	if err != nil {
 		t.Errorf("Should not return error on success: %s", err)
 	}
+
+	if len(checks) != 3 {
+		t.Fatalf("Expected a check per expectation, got %d", len(checks))
+	}
+
+	for _, check := range checks {
+		if !check.Passed {
+			t.Errorf("Check %s did not pass: %s", check, check.Message)
+		}
+	}
 }
 
 func TestEvaluateStatusCode(t *testing.T) {
@@ -166,3 +176,96 @@ func TestEvaluateBodyCompileError(t *testing.T) {
 		t.Errorf("Should not return nil error on failure")
 	}
 }
+
+func TestEvaluateCookies(t *testing.T) {
+	e := Expected{
+		Cookies: map[string]string{
+			"session": "^[a-z0-9]+$",
+		},
+	}
+
+	err := e.EvaluateCookies([]*http.Cookie{&http.Cookie{Name: "session", Value: "abc123"}})
+
+	if err != nil {
+		t.Errorf("Should not return error on success: %s", err)
+	}
+}
+
+func TestEvaluateCookiesMissing(t *testing.T) {
+	e := Expected{
+		Cookies: map[string]string{
+			"session": ".*",
+		},
+	}
+
+	err := e.EvaluateCookies([]*http.Cookie{&http.Cookie{Name: "other", Value: "abc123"}})
+
+	if err == nil {
+		t.Errorf("Should not return nil error on failure")
+	}
+}
+
+func TestEvaluateMissmatch(t *testing.T) {
+	e := Expected{
+		StatusCode: "2[0-9]{2}",
+		Body:       "[0-9]+",
+	}
+
+	r := http.Response{
+		StatusCode: 500,
+		Header:     http.Header{},
+	}
+
+	checks, err := e.Evaluate("some name", &r, "abc")
+
+	if err == nil {
+		t.Error("Should not return nil error on failure")
+	}
+
+	expected := []string{
+		"Status code 500, did not match 2[0-9]{2}",
+		`Body "abc", did not match [0-9]+`,
+	}
+
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %d", len(expected), len(checks))
+	}
+
+	for i, e := range expected {
+		if checks[i].Passed || checks[i].Message != e {
+			t.Errorf("Check %d did not match\n%s\n%s", i, checks[i].Message, e)
+		}
+	}
+}
+
+func TestEvaluateHeadersMessage(t *testing.T) {
+	e := Expected{
+		Headers: map[string]string{
+			"Content-Type": "^application/json$",
+			"X-Trace":      ".+",
+		},
+		Cookies: map[string]string{
+			"session": ".+",
+		},
+	}
+
+	r := http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Content-Type": []string{"text/html"},
+			"X-Trace":      []string{"abc"},
+		},
+	}
+
+	checks, err := e.Evaluate("some name", &r, "")
+
+	expected := `Header Content-Type "text/html", did not match ^application/json$, Cookie session was not set`
+
+	if err == nil || err.Error() != expected {
+		t.Errorf("Error did not match\n%v\n%s", err, expected)
+	}
+
+	if len(checks) != 3 || !checks[1].Passed || checks[1].Name != "X-Trace" {
+		t.Errorf("Checks were not sorted by header name: %v", checks)
+	}
+}
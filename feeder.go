@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	FeederSequential = "sequential"
+	FeederRandom     = "random"
+	FeederUnique     = "unique"
+
+	FeederWrap       = "wrap"
+	FeederStopRun    = "stop_run"
+	FeederStopWorker = "stop_worker"
+)
+
+type Feeder struct {
+	File      string `yaml:"file"`
+	Format    string `yaml:"format"`
+	Strategy  string `yaml:"strategy"`
+	Exhausted string `yaml:"exhausted"`
+	Name      string `yaml:"-"`
+	rows      []*Row
+	index     int
+	mutex     sync.Mutex
+}
+
+func (f *Feeder) Validate() error {
+	if f.File == "" {
+		return fmt.Errorf("Feeder %q is missing file", f.Name)
+	}
+
+	switch f.Strategy {
+	case "", FeederSequential, FeederRandom, FeederUnique:
+	default:
+		return fmt.Errorf("Feeder %q has unknown strategy %q", f.Name, f.Strategy)
+	}
+
+	switch f.Exhausted {
+	case "", FeederWrap, FeederStopRun, FeederStopWorker:
+	default:
+		return fmt.Errorf("Feeder %q has unknown exhausted policy %q", f.Name, f.Exhausted)
+	}
+
+	return nil
+}
+
+// Load reads the rows of the feeder file. Relative paths are resolved from
+// dir, which is the directory of the targets file.
+func (f *Feeder) Load(dir string) error {
+	filename := resolvePath(dir, f.File)
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	format := f.Format
+
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filename), ".")
+	}
+
+	var rows []*Row
+
+	switch format {
+	case "csv":
+		rows, err = readCsvRows(file)
+	case "jsonl", "ndjson":
+		rows, err = readJsonlRows(file)
+	default:
+		return fmt.Errorf("Feeder %q has unknown format %q", f.Name, format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Feeder %q: %s", f.Name, err)
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("Feeder %q has no rows", f.Name)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.rows = rows
+	f.index = 0
+
+	return nil
+}
+
+func (f *Feeder) Random() *Row {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.rows[rand.Intn(len(f.rows))]
+}
+
+// Take returns the next row, shared between all workers, or an error when
+// the rows are exhausted and the feeder isn't set to wrap.
+func (f *Feeder) Take() (*Row, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.index >= len(f.rows) {
+		switch f.Exhausted {
+		case FeederStopRun:
+			return nil, fmt.Errorf("Feeder %q is exhausted: %w", f.Name, ErrStopRun)
+		case FeederStopWorker:
+			return nil, fmt.Errorf("Feeder %q is exhausted: %w", f.Name, ErrStopWorker)
+		}
+
+		f.index = 0
+	}
+
+	row := f.rows[f.index]
+	f.index++
+
+	return row, nil
+}
+
+type Row struct {
+	Values map[string]string
+	Json   string
+}
+
+func (r *Row) Get(column string) string {
+	if r.Values != nil {
+		return r.Values[column]
+	}
+
+	return gjson.Get(r.Json, column).String()
+}
+
+func readCsvRows(reader io.Reader) ([]*Row, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]*Row, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		values := make(map[string]string)
+
+		for i, column := range header {
+			if i < len(record) {
+				values[column] = record[i]
+			}
+		}
+
+		rows = append(rows, &Row{Values: values})
+	}
+
+	return rows, nil
+}
+
+func readJsonlRows(reader io.Reader) ([]*Row, error) {
+	var rows []*Row
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" {
+			continue
+		}
+
+		if !gjson.Valid(text) {
+			return nil, fmt.Errorf("line %d is not valid json", line)
+		}
+
+		rows = append(rows, &Row{Json: text})
+	}
+
+	return rows, scanner.Err()
+}
+
+// Feed holds the rows of a single worker, drawn from the feeders used by
+// its scenario at the start of every iteration.
+type Feed struct {
+	Feeders  map[string]*Feeder
+	Names    []string
+	rows     map[string]*Row
+	assigned map[string]*Row
+}
+
+func NewFeed(feeders map[string]*Feeder, names []string) *Feed {
+	return &Feed{
+		Feeders:  feeders,
+		Names:    names,
+		rows:     make(map[string]*Row),
+		assigned: make(map[string]*Row),
+	}
+}
+
+func (f *Feed) Next() error {
+	for _, name := range f.Names {
+		feeder := f.Feeders[name]
+
+		if feeder == nil {
+			continue
+		}
+
+		switch feeder.Strategy {
+		case FeederRandom:
+			f.rows[name] = feeder.Random()
+		case FeederUnique:
+			row, ok := f.assigned[name]
+
+			if !ok {
+				var err error
+				row, err = feeder.Take()
+
+				if err != nil {
+					FeederExhaustedError.Inc()
+					return err
+				}
+
+				f.assigned[name] = row
+			}
+
+			f.rows[name] = row
+		default:
+			row, err := feeder.Take()
+
+			if err != nil {
+				FeederExhaustedError.Inc()
+				return err
+			}
+
+			f.rows[name] = row
+		}
+	}
+
+	return nil
+}
+
+func (f *Feed) Get(name, column string) (string, bool) {
+	row, ok := f.rows[name]
+
+	if !ok {
+		return "", false
+	}
+
+	return row.Get(column), true
+}
+
+// templateFeeders returns the names of the feeders used in the inputs
+func templateFeeders(inputs []string) []string {
+	found := make(map[string]bool)
+
+	for _, input := range inputs {
+		tmpl, err := template.
+			New("feeders").
+			Funcs(NewHistory().Funcs()).
+			Parse(input)
+
+		if err != nil {
+			continue
+		}
+
+		templateCalls(tmpl.Tree.Root, "feed", func(args []parse.Node) {
+			if len(args) > 0 {
+				if name, ok := args[0].(*parse.StringNode); ok {
+					found[name.Text] = true
+				}
+			}
+		})
+	}
+
+	names := make([]string, 0, len(found))
+
+	for name := range found {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFeederFile(t *testing.T, dir, name, content string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeederLoadCsvAndJsonl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	writeFeederFile(t, dir, "users.csv", "email,password\na@b.c,secret\nd@e.f,public\n")
+	writeFeederFile(t, dir, "users.jsonl", `{"email":"a@b.c","address":{"city":"Gbg"}}`+"\n\n"+`{"email":"d@e.f"}`+"\n")
+
+	csv := Feeder{Name: "csv", File: "users.csv"}
+
+	if err := csv.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonl := Feeder{Name: "jsonl", File: "users.jsonl"}
+
+	if err := jsonl.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(csv.rows) != 2 || csv.rows[1].Get("password") != "public" {
+		t.Error("Csv rows did not match")
+	}
+
+	if len(jsonl.rows) != 2 || jsonl.rows[0].Get("address.city") != "Gbg" {
+		t.Error("Jsonl rows did not match")
+	}
+
+	unknown := Feeder{Name: "unknown", File: "users.txt"}
+
+	if unknown.Load(dir) == nil {
+		t.Error("Unknown format should return an error")
+	}
+}
+
+func feederWithRows(strategy, exhausted string, values ...string) *Feeder {
+	f := &Feeder{Name: "users", Strategy: strategy, Exhausted: exhausted}
+
+	for _, v := range values {
+		f.rows = append(f.rows, &Row{Values: map[string]string{"id": v}})
+	}
+
+	return f
+}
+
+func TestFeederSequentialWrap(t *testing.T) {
+	feeders := map[string]*Feeder{"users": feederWithRows(FeederSequential, FeederWrap, "1", "2")}
+	feed := NewFeed(feeders, []string{"users"})
+	o := ""
+
+	for i := 0; i < 5; i++ {
+		if err := feed.Next(); err != nil {
+			t.Fatal(err)
+		}
+
+		v, _ := feed.Get("users", "id")
+		o += v
+	}
+
+	if o != "12121" {
+		t.Errorf("Sequential feeder returned rows in wrong order: %s", o)
+	}
+}
+
+func TestFeederExhausted(t *testing.T) {
+	for exhausted, expected := range map[string]error{
+		FeederStopRun:    ErrStopRun,
+		FeederStopWorker: ErrStopWorker,
+	} {
+		feeders := map[string]*Feeder{"users": feederWithRows(FeederSequential, exhausted, "1")}
+		feed := NewFeed(feeders, []string{"users"})
+
+		if err := feed.Next(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := feed.Next(); !errors.Is(err, expected) {
+			t.Errorf("Exhausted %s returned %v", exhausted, err)
+		}
+	}
+}
+
+func TestFeederUnique(t *testing.T) {
+	feeders := map[string]*Feeder{"users": feederWithRows(FeederUnique, FeederStopWorker, "1", "2")}
+	first := NewFeed(feeders, []string{"users"})
+	second := NewFeed(feeders, []string{"users"})
+	third := NewFeed(feeders, []string{"users"})
+
+	for i := 0; i < 3; i++ {
+		if first.Next() != nil || second.Next() != nil {
+			t.Fatal("Unique feeder should keep its row between iterations")
+		}
+	}
+
+	a, _ := first.Get("users", "id")
+	b, _ := second.Get("users", "id")
+
+	if a != "1" || b != "2" {
+		t.Errorf("Unique feeder did not give every worker its own row: %s %s", a, b)
+	}
+
+	if !errors.Is(third.Next(), ErrStopWorker) {
+		t.Error("Unique feeder should stop the worker without a row")
+	}
+}
+
+func TestFeedTemplateFunc(t *testing.T) {
+	feeders := map[string]*Feeder{"users": feederWithRows(FeederRandom, "", "1")}
+	history := NewHistory()
+	history.Feed = NewFeed(feeders, []string{"users"})
+
+	if err := history.Feed.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := history.Parse(`{{ feed "users" "id" }}{{ feed "missing" "id" }}`)
+
+	if output != "1" {
+		t.Errorf("Feed template func returned %s", output)
+	}
+}
+
+func TestTemplateFeeders(t *testing.T) {
+	names := templateFeeders([]string{
+		`{{ feed "users" "email" }}`,
+		`{{ if true }}{{ feed "cards" "number" }}{{ end }}{{ feed "users" "password" }}`,
+		`{{ broken`,
+	})
+
+	if len(names) != 2 || names[0] != "cards" || names[1] != "users" {
+		t.Errorf("Template feeders did not match: %v", names)
+	}
+}
@@ -13,12 +13,16 @@ import (
 type HistoryHandler interface {
 	Record(name, body string)
 	Parse(input string) string
+	SetItem(index int, item string)
 }
 
 var historyHandler HistoryHandler = &History{}
 
 type History struct {
 	Records map[string]*Record
+	Feed    *Feed
+	Session *Session
+	Item    *Item
 }
 
 func NewHistory() *History {
@@ -33,67 +37,21 @@ func (h *History) Record(name, result string) {
 	}
 }
 
+// SetItem sets the current element of a foreach request. A negative index
+// clears it.
+func (h *History) SetItem(index int, item string) {
+	if index < 0 {
+		h.Item = nil
+		return
+	}
+
+	h.Item = &Item{Index: index, Value: item}
+}
+
 func (h *History) Parse(input string) string {
 	tmpl, err := template.
 		New("History parser").
-		Funcs(template.FuncMap{
-			"fromJson": func(name, path string) string {
-				r := h.From(name)
-
-				if r != nil {
-					return r.Json(path)
-				}
-
-				MissingTemplateEntryError.Inc()
-				logrus.
-					WithField("function", "fromJson").
-					WithField("entry", name).
-					WithField("path", path).
-					Error("Missing json template")
-				return ""
-			},
-			"uuid": func() uuid.UUID {
-				return uuid.New()
-			},
-			"now": func() time.Time {
-				return time.Now()
-			},
-			"add": func(values ...int) int {
-				add := 0
-
-				for _, v := range values {
-					add += v
-				}
-
-				return add
-			},
-			"sub": func(values ...int) int {
-				if len(values) <= 0 {
-					return 0
-				}
-
-				sub := values[0]
-
-				for i := 1; i < len(values); i++ {
-					sub -= values[i]
-				}
-
-				return sub
-			},
-			"mul": func(values ...int) int {
-				if len(values) <= 0 {
-					return 0
-				}
-
-				mul := values[0]
-
-				for i := 1; i < len(values); i++ {
-					mul *= values[i]
-				}
-
-				return mul
-			},
-		}).
+		Funcs(h.Funcs()).
 		Parse(input)
 
 	if err != nil {
@@ -118,6 +76,114 @@ func (h *History) Parse(input string) string {
 	return buf.String()
 }
 
+func (h *History) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"fromJson": func(name, path string) string {
+			r := h.From(name)
+
+			if r != nil {
+				return r.Json(path)
+			}
+
+			MissingTemplateEntryError.Inc()
+			logrus.
+				WithField("function", "fromJson").
+				WithField("entry", name).
+				WithField("path", path).
+				Error("Missing json template")
+			return ""
+		},
+		"feed": func(name, column string) string {
+			if h.Feed != nil {
+				if value, ok := h.Feed.Get(name, column); ok {
+					return value
+				}
+			}
+
+			MissingTemplateEntryError.Inc()
+			logrus.
+				WithField("function", "feed").
+				WithField("feeder", name).
+				WithField("column", column).
+				Error("Missing feeder row")
+			return ""
+		},
+		"cookie": func(name string) string {
+			if h.Session != nil {
+				if value, ok := h.Session.Cookie(name); ok {
+					return value
+				}
+			}
+
+			MissingTemplateEntryError.Inc()
+			logrus.
+				WithField("function", "cookie").
+				WithField("cookie", name).
+				Error("Missing cookie")
+			return ""
+		},
+		"item": func(path ...string) string {
+			if h.Item != nil {
+				return h.Item.Get(path...)
+			}
+
+			MissingTemplateEntryError.Inc()
+			logrus.
+				WithField("function", "item").
+				Error("Missing foreach item")
+			return ""
+		},
+		"itemIndex": func() int {
+			if h.Item != nil {
+				return h.Item.Index
+			}
+
+			return -1
+		},
+		"uuid": func() uuid.UUID {
+			return uuid.New()
+		},
+		"now": func() time.Time {
+			return time.Now()
+		},
+		"add": func(values ...int) int {
+			add := 0
+
+			for _, v := range values {
+				add += v
+			}
+
+			return add
+		},
+		"sub": func(values ...int) int {
+			if len(values) <= 0 {
+				return 0
+			}
+
+			sub := values[0]
+
+			for i := 1; i < len(values); i++ {
+				sub -= values[i]
+			}
+
+			return sub
+		},
+		"mul": func(values ...int) int {
+			if len(values) <= 0 {
+				return 0
+			}
+
+			mul := values[0]
+
+			for i := 1; i < len(values); i++ {
+				mul *= values[i]
+			}
+
+			return mul
+		},
+	}
+}
+
 func (h *History) From(name string) *Record {
 	return h.Records[name]
 }
@@ -129,3 +195,18 @@ type Record struct {
 func (r *Record) Json(path string) string {
 	return gjson.Get(r.Body, path).String()
 }
+
+type Item struct {
+	Index int
+	Value string
+}
+
+// Get returns the value of the item, or the value at the gjson path when
+// given one
+func (i *Item) Get(path ...string) string {
+	if len(path) == 0 {
+		return i.Value
+	}
+
+	return gjson.Get(i.Value, path[0]).String()
+}
@@ -88,3 +88,22 @@ func TestMulTemplateFuncs(t *testing.T) {
 		t.Errorf("Mul didn't match, returned %s", output)
 	}
 }
+
+func TestItemTemplateFuncs(t *testing.T) {
+	input := `{{ itemIndex }}:{{ item }}:{{ item "id" }}`
+
+	history := NewHistory()
+	history.SetItem(1, `{"id":"b"}`)
+
+	output := history.Parse(input)
+
+	if output != `1:{"id":"b"}:b` {
+		t.Errorf("Parser did not render the foreach item: %s", output)
+	}
+
+	history.SetItem(-1, "")
+
+	if output := history.Parse(`{{ itemIndex }}`); output != "-1" {
+		t.Errorf("Foreach item was not cleared: %s", output)
+	}
+}
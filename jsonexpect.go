@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var jsonTypes = []string{"string", "number", "boolean", "array", "object", "null"}
+
+// JSONAssertion is a named check of a single value of a json body, picked by
+// a gjson path. Every condition set must hold.
+type JSONAssertion struct {
+	Name      string   `yaml:"name"`
+	Path      string   `yaml:"path"`
+	Exists    *bool    `yaml:"exists"`
+	Equals    *string  `yaml:"equals"`
+	NotEquals *string  `yaml:"not_equals"`
+	GT        *float64 `yaml:"gt"`
+	GTE       *float64 `yaml:"gte"`
+	LT        *float64 `yaml:"lt"`
+	LTE       *float64 `yaml:"lte"`
+	Length    *int     `yaml:"length"`
+	Type      string   `yaml:"type"`
+	Matches   string   `yaml:"matches"`
+}
+
+// GetName returns the name of the assertion, which defaults to its path
+func (a *JSONAssertion) GetName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+
+	return a.Path
+}
+
+func (a *JSONAssertion) Validate() error {
+	if a.Path == "" {
+		return errors.New("JSON assertion must have a path")
+	}
+
+	conditions := a.conditions()
+
+	if len(conditions) == 0 {
+		return fmt.Errorf("JSON assertion %s must have a condition", a.GetName())
+	}
+
+	if a.Exists != nil && !*a.Exists && len(conditions) > 1 {
+		return fmt.Errorf("JSON assertion %s can't expect a value not to exist and have other conditions", a.GetName())
+	}
+
+	if a.Type != "" && !containsString(jsonTypes, a.Type) {
+		return fmt.Errorf("JSON assertion %s type must be %s, not %q", a.GetName(), strings.Join(jsonTypes, ", "), a.Type)
+	}
+
+	if a.Length != nil && *a.Length < 0 {
+		return fmt.Errorf("JSON assertion %s length can't be negative", a.GetName())
+	}
+
+	if a.Matches != "" {
+		_, err := regexp.Compile(a.Matches)
+
+		if err != nil {
+			return fmt.Errorf("JSON assertion %s matches: %s", a.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// Check checks the value at the path of body against every condition, and
+// fails with the first that doesn't hold
+func (a *JSONAssertion) Check(body string) *Check {
+	check := &Check{
+		Part:     "json",
+		Name:     a.GetName(),
+		Expected: strings.Join(a.conditions(), ", "),
+	}
+
+	value := gjson.Get(body, a.Path)
+
+	if a.Exists != nil && !*a.Exists {
+		if check.Passed = !value.Exists(); !check.Passed {
+			check.Message = fmt.Sprintf("JSON %s %s, was expected not to exist", a.GetName(), value.Raw)
+		}
+
+		return check
+	}
+
+	if !value.Exists() {
+		check.Message = fmt.Sprintf("JSON %s does not exist", a.GetName())
+		return check
+	}
+
+	check.Message = a.failure(value)
+	check.Passed = check.Message == ""
+
+	return check
+}
+
+// failure returns why value doesn't meet the conditions, or empty if it does
+func (a *JSONAssertion) failure(value gjson.Result) string {
+	name := a.GetName()
+
+	if a.Type != "" && jsonType(value) != a.Type {
+		return fmt.Sprintf("JSON %s %s is %s, not %s", name, value.Raw, jsonType(value), a.Type)
+	}
+
+	if a.Equals != nil && value.String() != *a.Equals {
+		return fmt.Sprintf("JSON %s %s, did not equal %s", name, value.Raw, *a.Equals)
+	}
+
+	if a.NotEquals != nil && value.String() == *a.NotEquals {
+		return fmt.Sprintf("JSON %s %s, was expected not to equal %s", name, value.Raw, *a.NotEquals)
+	}
+
+	if a.GT != nil || a.GTE != nil || a.LT != nil || a.LTE != nil {
+		if value.Type != gjson.Number {
+			return fmt.Sprintf("JSON %s %s is %s, not number", name, value.Raw, jsonType(value))
+		}
+
+		number := value.Float()
+
+		for _, c := range []struct {
+			operator string
+			bound    *float64
+			holds    func(float64) bool
+		}{
+			{">", a.GT, func(bound float64) bool { return number > bound }},
+			{">=", a.GTE, func(bound float64) bool { return number >= bound }},
+			{"<", a.LT, func(bound float64) bool { return number < bound }},
+			{"<=", a.LTE, func(bound float64) bool { return number <= bound }},
+		} {
+			if c.bound != nil && !c.holds(*c.bound) {
+				return fmt.Sprintf("JSON %s %s, was expected to be %s %s", name, value.Raw, c.operator, formatFloat(*c.bound))
+			}
+		}
+	}
+
+	if a.Length != nil {
+		length, ok := jsonLength(value)
+
+		if !ok {
+			return fmt.Sprintf("JSON %s %s is %s, which has no length", name, value.Raw, jsonType(value))
+		}
+
+		if length != *a.Length {
+			return fmt.Sprintf("JSON %s has length %d, not %d", name, length, *a.Length)
+		}
+	}
+
+	if a.Matches != "" && !match(a.Matches, value.String()) {
+		return fmt.Sprintf("JSON %s %s, did not match %s", name, value.Raw, a.Matches)
+	}
+
+	return ""
+}
+
+// conditions describes every condition set, like exists or > 10
+func (a *JSONAssertion) conditions() []string {
+	var conditions []string
+
+	if a.Exists != nil {
+		if *a.Exists {
+			conditions = append(conditions, "exists")
+		} else {
+			conditions = append(conditions, "does not exist")
+		}
+	}
+
+	if a.Type != "" {
+		conditions = append(conditions, "type "+a.Type)
+	}
+
+	if a.Equals != nil {
+		conditions = append(conditions, "equals "+*a.Equals)
+	}
+
+	if a.NotEquals != nil {
+		conditions = append(conditions, "not equals "+*a.NotEquals)
+	}
+
+	for _, c := range []struct {
+		operator string
+		bound    *float64
+	}{{">", a.GT}, {">=", a.GTE}, {"<", a.LT}, {"<=", a.LTE}} {
+		if c.bound != nil {
+			conditions = append(conditions, c.operator+" "+formatFloat(*c.bound))
+		}
+	}
+
+	if a.Length != nil {
+		conditions = append(conditions, fmt.Sprintf("length %d", *a.Length))
+	}
+
+	if a.Matches != "" {
+		conditions = append(conditions, "matches "+a.Matches)
+	}
+
+	return conditions
+}
+
+func jsonType(value gjson.Result) string {
+	switch value.Type {
+	case gjson.Null:
+		return "null"
+	case gjson.False, gjson.True:
+		return "boolean"
+	case gjson.Number:
+		return "number"
+	case gjson.String:
+		return "string"
+	}
+
+	if value.IsArray() {
+		return "array"
+	}
+
+	return "object"
+}
+
+// jsonLength returns the number of elements of an array or object, or the
+// number of characters of a string
+func jsonLength(value gjson.Result) (int, bool) {
+	switch {
+	case value.IsArray():
+		return len(value.Array()), true
+	case value.IsObject():
+		return len(value.Map()), true
+	case value.Type == gjson.String:
+		return len([]rune(value.String())), true
+	}
+
+	return 0, false
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestJSONAssertionCheck(t *testing.T) {
+	body := `{"user":{"id":42,"name":"Alice","email":"alice@example.com","admin":false},"items":[1,2,3],"deleted":null}`
+
+	tests := []struct {
+		assertion string
+		message   string
+	}{
+		{"path: user.id\nexists: true", ""},
+		{"path: user.password\nexists: false", ""},
+		{"path: user.name\nequals: Alice", ""},
+		{"path: user.admin\nequals: false", ""},
+		{"path: user.id\nequals: 42", ""},
+		{"path: user.name\nnot_equals: Bob", ""},
+		{"path: user.id\ngt: 10\nlte: 42", ""},
+		{"path: items\nlength: 3", ""},
+		{"path: user\nlength: 4\ntype: object", ""},
+		{"path: deleted\ntype: \"null\"", ""},
+		{"path: user.email\nmatches: '@example\\.com$'", ""},
+		{"path: user.token\nexists: true", "JSON user.token does not exist"},
+		{"path: user.id\nexists: false", "JSON user.id 42, was expected not to exist"},
+		{"name: user name\npath: user.name\nequals: Bob", `JSON user name "Alice", did not equal Bob`},
+		{"path: user.name\nnot_equals: Alice", `JSON user.name "Alice", was expected not to equal Alice`},
+		{"path: user.id\ngt: 10\nlt: 40", "JSON user.id 42, was expected to be < 40"},
+		{"path: user.name\ngte: 1", `JSON user.name "Alice" is string, not number`},
+		{"path: items\nlength: 2", "JSON items has length 3, not 2"},
+		{"path: user.id\nlength: 2", "JSON user.id 42 is number, which has no length"},
+		{"path: items\ntype: object", "JSON items [1,2,3] is array, not object"},
+		{"path: user.email\nmatches: '@example\\.org$'", `JSON user.email "alice@example.com", did not match @example\.org$`},
+	}
+
+	for _, test := range tests {
+		var assertion JSONAssertion
+
+		if err := yaml.Unmarshal([]byte(test.assertion), &assertion); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := assertion.Validate(); err != nil {
+			t.Fatalf("Assertion was not valid: %s", err)
+		}
+
+		check := assertion.Check(body)
+
+		if check.Passed != (test.message == "") || check.Message != test.message {
+			t.Errorf("Assertion %q did not match\n%s\n%s", test.assertion, check.Message, test.message)
+		}
+	}
+}
+
+func TestJSONAssertionValidate(t *testing.T) {
+	exists := false
+	equals := "a"
+	length := -1
+
+	for _, assertion := range []JSONAssertion{
+		{Equals: &equals},
+		{Path: "id"},
+		{Path: "id", Exists: &exists, Equals: &equals},
+		{Path: "id", Type: "integer"},
+		{Path: "id", Length: &length},
+		{Path: "id", Matches: "[a-"},
+	} {
+		if assertion.Validate() == nil {
+			t.Errorf("Invalid assertion was valid: %+v", assertion)
+		}
+	}
+}
+
+func TestEvaluateJSON(t *testing.T) {
+	content := []byte(`
+json:
+  - name: has user
+    path: user.id
+    exists: true
+  - path: items
+    length: 1
+`)
+
+	var e Expected
+
+	if err := yaml.Unmarshal(content, &e); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Scenario = "json"
+	failed := JSONAssertionsCounter.WithLabelValues("json", "list", "items", "failed")
+	passed := JSONAssertionsCounter.WithLabelValues("json", "list", "has user", "passed")
+
+	r := http.Response{StatusCode: 200, Header: http.Header{}}
+	checks, err := e.Evaluate("list", &r, `{"user":{"id":1},"items":[]}`)
+
+	if err == nil || err.Error() != "JSON items has length 0, not 1" {
+		t.Errorf("Failed assertion was not returned: %v", err)
+	}
+
+	if len(checks) != 2 || checks[0].Name != "has user" || checks[0].Expected != "exists" {
+		t.Errorf("Assertions were not named checks: %v", checks)
+	}
+
+	if counterValue(failed) != 1 || counterValue(passed) != 1 {
+		t.Error("Assertions were not counted by name")
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JUnit aggregates the checks of every request, to report them as a JUnit
+// XML file where every request is a test case of its scenario
+type JUnit struct {
+	Started  time.Time
+	Requests map[string]*JUnitCase
+	mutex    sync.Mutex
+}
+
+func NewJUnit() *JUnit {
+	return &JUnit{
+		Started:  time.Now(),
+		Requests: make(map[string]*JUnitCase),
+	}
+}
+
+func (j *JUnit) Handle(result *Result) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	key := result.Scenario + "\x00" + result.Name
+	request, ok := j.Requests[key]
+
+	if !ok {
+		request = &JUnitCase{
+			Scenario: result.Scenario,
+			Name:     result.Name,
+			Checks:   make(map[string]*JUnitCheck),
+			Errors:   make(map[string]int),
+		}
+		j.Requests[key] = request
+	}
+
+	request.Add(result)
+}
+
+// Write writes the report to filename
+func (j *JUnit) Write(filename string) error {
+	data, err := xml.MarshalIndent(j.Report(), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append([]byte(xml.Header), data...), 0644)
+}
+
+// Report returns the test suites of every scenario, sorted by name
+func (j *JUnit) Report() *JUnitTestSuites {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	suites := make(map[string]*JUnitTestSuite)
+	report := &JUnitTestSuites{
+		Name: "goload",
+		Time: formatJUnitTime(time.Since(j.Started).Seconds()),
+	}
+
+	for _, request := range j.Requests {
+		suite, ok := suites[request.Scenario]
+
+		if !ok {
+			suite = &JUnitTestSuite{Name: request.Scenario}
+			suites[request.Scenario] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		testCase := request.TestCase()
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suite.time += request.Time
+		report.Tests++
+
+		if testCase.Failure != nil {
+			suite.Failures++
+			report.Failures++
+		}
+
+		if testCase.Error != nil {
+			suite.Errors++
+			report.Errors++
+		}
+	}
+
+	sort.Slice(report.Suites, func(i, k int) bool {
+		return report.Suites[i].Name < report.Suites[k].Name
+	})
+
+	for _, suite := range report.Suites {
+		suite.Time = formatJUnitTime(suite.time)
+
+		sort.Slice(suite.Cases, func(i, k int) bool {
+			return suite.Cases[i].Name < suite.Cases[k].Name
+		})
+	}
+
+	return report
+}
+
+// JUnitCase is the outcome of every time a request was sent
+type JUnitCase struct {
+	Scenario string
+	Name     string
+	Count    int
+	Time     float64
+	Checks   map[string]*JUnitCheck
+	Errors   map[string]int
+}
+
+func (c *JUnitCase) Add(result *Result) {
+	c.Count++
+	c.Time += result.Latency
+
+	if result.Error != "" {
+		addCapped(c.Errors, result.Error)
+		return
+	}
+
+	for _, check := range result.Checks {
+		key := check.String()
+		outcome, ok := c.Checks[key]
+
+		if !ok {
+			outcome = &JUnitCheck{
+				Check:    key,
+				Failures: make(map[string]int),
+			}
+			c.Checks[key] = outcome
+		}
+
+		if check.Passed {
+			outcome.Passed++
+		} else {
+			outcome.Failed++
+			addCapped(outcome.Failures, check.Message)
+		}
+	}
+}
+
+// TestCase reports the request as failed if any check failed, with every
+// check and its failure messages, and as an error if it could not be sent
+func (c *JUnitCase) TestCase() *JUnitTestCase {
+	testCase := &JUnitTestCase{
+		Name:      c.Name,
+		Classname: c.Scenario,
+		Time:      formatJUnitTime(c.Time),
+	}
+
+	keys := make([]string, 0, len(c.Checks))
+	failed := 0
+
+	for key, check := range c.Checks {
+		keys = append(keys, key)
+
+		if check.Failed > 0 {
+			failed++
+		}
+	}
+
+	sort.Strings(keys)
+
+	var out, failures strings.Builder
+
+	fmt.Fprintf(&out, "Sent %d times\n", c.Count)
+
+	for _, key := range keys {
+		check := c.Checks[key]
+
+		fmt.Fprintf(&out, "%s: %d passed, %d failed\n", key, check.Passed, check.Failed)
+
+		if check.Failed == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&failures, "%s: %d of %d failed\n", key, check.Failed, check.Passed+check.Failed)
+
+		for _, message := range sortedErrors(check.Failures) {
+			fmt.Fprintf(&failures, "  %d  %s\n", check.Failures[message], message)
+		}
+	}
+
+	testCase.SystemOut = out.String()
+
+	if failed > 0 {
+		testCase.Failure = &JUnitFailure{
+			Message: fmt.Sprintf("%d of %d checks failed", failed, len(keys)),
+			Type:    "expectation",
+			Text:    failures.String(),
+		}
+	}
+
+	if len(c.Errors) > 0 {
+		var errs strings.Builder
+		count := 0
+
+		for _, message := range sortedErrors(c.Errors) {
+			count += c.Errors[message]
+			fmt.Fprintf(&errs, "  %d  %s\n", c.Errors[message], message)
+		}
+
+		testCase.Error = &JUnitFailure{
+			Message: fmt.Sprintf("%d of %d requests failed", count, c.Count),
+			Type:    "error",
+			Text:    errs.String(),
+		}
+	}
+
+	return testCase
+}
+
+// JUnitCheck counts the outcomes of a single check of a request
+type JUnitCheck struct {
+	Check    string
+	Passed   int
+	Failed   int
+	Failures map[string]int
+}
+
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*JUnitTestCase `xml:"testcase"`
+	time     float64
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Error     *JUnitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func formatJUnitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// addCapped counts message, or other errors when there already are
+// SummaryErrors distinct messages
+func addCapped(messages map[string]int, message string) {
+	if _, ok := messages[message]; !ok && len(messages) >= SummaryErrors {
+		message = SummaryOtherErrors
+	}
+
+	messages[message]++
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJUnitReport(t *testing.T) {
+	junit := NewJUnit()
+
+	passed := &Check{Part: "status_code", Expected: "2..", Passed: true}
+	failed := &Check{
+		Part:     "body",
+		Expected: "ok",
+		Message:  `Body "nope", did not match ok`,
+	}
+
+	junit.Handle(&Result{Scenario: "browse", Name: "start", Latency: 0.1, Checks: []*Check{passed}})
+	junit.Handle(&Result{Scenario: "browse", Name: "search", Latency: 0.1, Checks: []*Check{passed, failed}})
+	junit.Handle(&Result{Scenario: "browse", Name: "search", Latency: 0.2, Checks: []*Check{passed}})
+	junit.Handle(&Result{Scenario: "checkout", Name: "pay", Status: "error", Error: "Failed"})
+
+	report := junit.Report()
+
+	if report.Tests != 3 || report.Failures != 1 || report.Errors != 1 {
+		t.Fatalf("Report did not count test cases: %+v", report)
+	}
+
+	if len(report.Suites) != 2 || report.Suites[0].Name != "browse" || report.Suites[0].Time != "0.400" {
+		t.Fatalf("Report did not contain a suite per scenario: %+v", report.Suites)
+	}
+
+	search := report.Suites[0].Cases[0]
+
+	if search.Name != "search" || search.Failure == nil || search.Failure.Message != "1 of 2 checks failed" {
+		t.Fatalf("Failed check was not reported: %+v", search)
+	}
+
+	if !strings.Contains(search.Failure.Text, "body ok: 1 of 1 failed") ||
+		!strings.Contains(search.Failure.Text, `1  Body "nope", did not match ok`) {
+		t.Errorf("Failure did not contain the mismatching value:\n%s", search.Failure.Text)
+	}
+
+	if !strings.Contains(search.SystemOut, "status_code 2..: 2 passed, 0 failed") {
+		t.Errorf("Passed check was not reported:\n%s", search.SystemOut)
+	}
+
+	if start := report.Suites[0].Cases[1]; start.Failure != nil || start.Error != nil {
+		t.Errorf("Passing request was reported as failed: %+v", start)
+	}
+
+	if pay := report.Suites[1].Cases[0]; pay.Error == nil || pay.Error.Message != "1 of 1 requests failed" {
+		t.Errorf("Failed request was not reported as an error: %+v", pay)
+	}
+}
+
+func TestJUnitWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	junit := NewJUnit()
+	junit.Handle(&Result{Scenario: "default", Name: "start", Checks: []*Check{
+		{Part: "headers", Name: "Content-Type", Expected: "json", Message: `Header Content-Type "text/html", did not match json`},
+	}})
+
+	filename := filepath.Join(dir, "report.xml")
+
+	if err := junit.Write(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report JUnitTestSuites
+
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Suites) != 1 || len(report.Suites[0].Cases) != 1 || report.Suites[0].Cases[0].Failure == nil {
+		t.Errorf("Written report does not match: %s", data)
+	}
+}
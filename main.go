@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
-	"strconv"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -26,12 +29,17 @@ var (
 	ExecuteTemplateError      = ErrorCounter.WithLabelValues("template_execute")
 	MissingTemplateEntryError = ErrorCounter.WithLabelValues("template_missing_entry")
 	ExpectReCompileError      = ErrorCounter.WithLabelValues("expect_re_compile")
+	FeederExhaustedError      = ErrorCounter.WithLabelValues("feeder_exhausted")
+	WhenEvaluateError         = ErrorCounter.WithLabelValues("when_evaluate")
+	ForeachNotArrayError      = ErrorCounter.WithLabelValues("foreach_not_array")
+	ResultsDroppedError       = ErrorCounter.WithLabelValues("results_dropped")
+	ResultsWriteError         = ErrorCounter.WithLabelValues("results_write")
 	RuntimeGauge              = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "goload_runtime",
 			Help: "Goload runtime with parameters",
 		},
-		[]string{"targets_length", "concurrency", "sleep", "repeat"},
+		[]string{"scenario", "targets_length", "concurrency", "sleep", "repeat"},
 	)
 	RequestLatencySummary = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
@@ -39,21 +47,120 @@ var (
 			Help:       "Goload http request latency in seconds",
 			Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.005, 0.99: 0.001},
 		},
-		[]string{"name", "status"},
+		[]string{"scenario", "name", "status"},
+	)
+	RequestLatencyHistogram    = newRequestLatencyHistogram(DefaultLatencyBuckets)
+	IterationDurationHistogram = newIterationDurationHistogram(DefaultIterationBuckets)
+	RequestPhaseHistogram      = newRequestPhaseHistogram(DefaultLatencyBuckets)
+	RequestConnectionsCounter  = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_request_connections_total",
+			Help: "Goload total requests by whether they reused a connection",
+		},
+		[]string{"scenario", "name", "reused"},
 	)
 	RequestStatusCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "goload_request_status_total",
 			Help: "Goload total requests by status code",
 		},
-		[]string{"name", "status"},
+		[]string{"scenario", "name", "status"},
+	)
+	RequestAttemptsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_request_attempts_total",
+			Help: "Goload total attempts of requests with a retry policy by status code",
+		},
+		[]string{"scenario", "name", "status"},
+	)
+	RequestRetryOutcomeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_request_retry_outcome_total",
+			Help: "Goload total final outcomes of requests with a retry policy",
+		},
+		[]string{"scenario", "name", "outcome"},
+	)
+	RequestSkippedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_request_skipped_total",
+			Help: "Goload total skipped requests by reason",
+		},
+		[]string{"scenario", "name", "reason"},
 	)
 	ExpectedResponseCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "goload_expected_response_total",
-			Help: "Goload total expected responses",
+			Help: "Goload total checks of expected responses that passed, and schema checks that failed as schema_failed",
+		},
+		[]string{"scenario", "name", "part"},
+	)
+	ExpectationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_expectations_total",
+			Help: "Goload total expectation checks by outcome - passed or failed",
+		},
+		[]string{"scenario", "name", "part", "outcome"},
+	)
+	JSONAssertionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_json_assertions_total",
+			Help: "Goload total json assertions on response bodies by outcome - passed or failed",
+		},
+		[]string{"scenario", "name", "assertion", "outcome"},
+	)
+	ProbeSuccessGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goload_probe_success",
+			Help: "Goload whether the last request succeeded and matched its expectations",
+		},
+		[]string{"scenario", "name"},
+	)
+	ScenarioLabelsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goload_scenario_labels",
+			Help: "Goload labels defined on scenarios",
+		},
+		[]string{"scenario", "label", "value"},
+	)
+	IterationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_iterations_total",
+			Help: "Goload total started iterations of scenarios",
+		},
+		[]string{"scenario"},
+	)
+	IterationsTargetRateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goload_iterations_target_rate",
+			Help: "Goload target rate of started iterations per second",
+		},
+		[]string{"scenario"},
+	)
+	ActiveVirtualUsersGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goload_active_virtual_users",
+			Help: "Goload number of workers currently running",
+		},
+		[]string{"scenario"},
+	)
+	IterationsDroppedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goload_iterations_dropped_total",
+			Help: "Goload total iterations not started since every worker was busy",
+		},
+		[]string{"scenario"},
+	)
+	ConfigReloadSuccessGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goload_config_reload_success",
+			Help: "Goload whether the last targets file load succeeded",
+		},
+	)
+	ConfigReloadTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goload_config_last_reload_success_timestamp_seconds",
+			Help: "Goload timestamp of the last successful targets file load",
 		},
-		[]string{"name", "part"},
 	)
 )
 
@@ -61,29 +168,76 @@ func init() {
 	prometheus.MustRegister(ErrorCounter)
 	prometheus.MustRegister(RuntimeGauge)
 	prometheus.MustRegister(RequestLatencySummary)
+	prometheus.MustRegister(RequestLatencyHistogram)
+	prometheus.MustRegister(IterationDurationHistogram)
+	prometheus.MustRegister(RequestPhaseHistogram)
+	prometheus.MustRegister(RequestConnectionsCounter)
 	prometheus.MustRegister(RequestStatusCounter)
+	prometheus.MustRegister(RequestAttemptsCounter)
+	prometheus.MustRegister(RequestRetryOutcomeCounter)
+	prometheus.MustRegister(RequestSkippedCounter)
 	prometheus.MustRegister(ExpectedResponseCounter)
+	prometheus.MustRegister(ExpectationsCounter)
+	prometheus.MustRegister(JSONAssertionsCounter)
+	prometheus.MustRegister(ProbeSuccessGauge)
+	prometheus.MustRegister(ScenarioLabelsGauge)
+	prometheus.MustRegister(IterationsCounter)
+	prometheus.MustRegister(IterationsTargetRateGauge)
+	prometheus.MustRegister(IterationsDroppedCounter)
+	prometheus.MustRegister(ActiveVirtualUsersGauge)
+	prometheus.MustRegister(ConfigReloadSuccessGauge)
+	prometheus.MustRegister(ConfigReloadTimestampGauge)
 
 	logrus.SetLevel(logrus.FatalLevel)
 	logrus.SetFormatter(&logrus.TextFormatter{})
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(Validate(os.Args[2:]))
+	}
+
 	var host string
 	var port int
 	var concurrency int
 	var sleep int
 	var repeat int
 	var targets string
+	var watch time.Duration
+	var timeout time.Duration
+	var duration time.Duration
+	var grace time.Duration
+	var summary bool
+	var summaryJSON string
+	var junit string
+	var html string
+	var out string
+	var outMaxSize int64
+	var buckets string
+	var iterationBuckets string
+	var latencySummary bool
 	var logLevel string
 	var logFormat string
 
 	flag.StringVar(&host, "host", "0.0.0.0", "Hostname")
 	flag.IntVar(&port, "port", 9115, "Port")
-	flag.IntVar(&concurrency, "concurrency", 1, "Concurrency")
-	flag.IntVar(&sleep, "sleep", 1, "Sleep")
-	flag.IntVar(&repeat, "repeat", -1, "Repeat, -1 <= infinite")
+	flag.IntVar(&concurrency, "concurrency", 1, "Concurrency, default for scenarios not setting it")
+	flag.IntVar(&sleep, "sleep", 1, "Sleep, default for scenarios not setting it")
+	flag.IntVar(&repeat, "repeat", -1, "Repeat, -1 <= infinite, default for scenarios not setting it")
 	flag.StringVar(&targets, "targets", "", "Targets path")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout, default for targets not setting it, 0 disables")
+	flag.DurationVar(&watch, "watch", 5*time.Second, "Interval for checking the targets file for changes, 0 disables")
+	flag.DurationVar(&duration, "duration", 0, "Duration of the run, 0 runs until every scenario is done or goload is stopped")
+	flag.DurationVar(&grace, "grace", 30*time.Second, "Time for in-flight iterations to finish when stopping")
+	flag.BoolVar(&summary, "summary", true, "Print a summary of the requests at the end of the run")
+	flag.StringVar(&summaryJSON, "summary-json", "", "Path to write the summary to as json")
+	flag.StringVar(&junit, "junit", "", "Path to write the checks of every request to as JUnit XML")
+	flag.StringVar(&html, "html", "", "Path to write a report of the run to as HTML")
+	flag.StringVar(&out, "out", "", "Path to write the result of every request to as json lines")
+	flag.Int64Var(&outMaxSize, "out-max-size", 100, "Size in MB at which the -out file is rotated, 0 disables")
+	flag.StringVar(&buckets, "buckets", "", "Comma separated buckets in seconds of the request latency histogram")
+	flag.StringVar(&iterationBuckets, "iteration-buckets", "", "Comma separated buckets in seconds of the iteration duration histogram")
+	flag.BoolVar(&latencySummary, "latency-summary", true, "Export request latencies as a summary too")
 	flag.StringVar(&logLevel, "loglevel", "warn", "Log level")
 	flag.StringVar(&logFormat, "logformat", "text", "Log format - text or json")
 
@@ -120,18 +274,180 @@ func main() {
 		WithField("sleep", sleep).
 		WithField("repeat", repeat).
 		WithField("targets", targets).
+		WithField("timeout", timeout.String()).
+		WithField("watch", watch.String()).
+		WithField("duration", duration.String()).
+		WithField("grace", grace.String()).
+		WithField("summary", summary).
+		WithField("summary-json", summaryJSON).
+		WithField("junit", junit).
+		WithField("html", html).
+		WithField("out", out).
+		WithField("out-max-size", outMaxSize).
+		WithField("buckets", buckets).
+		WithField("iteration-buckets", iterationBuckets).
+		WithField("latency-summary", latencySummary).
 		WithField("loglevel", logLevel).
 		WithField("logformat", logFormat).
 		Debug("Started Goload")
 
-	closer := make(chan bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	run := NewRun()
+	results := NewSummary()
+	run.Results = append(run.Results, results)
+
+	checks := NewJUnit()
+
+	if junit != "" {
+		run.Results = append(run.Results, checks)
+	}
+
+	timeline := NewTimeline(time.Second)
+
+	if html != "" {
+		run.Results = append(run.Results, timeline)
+	}
+
+	var writer *ResultWriter
+
+	if out != "" {
+		writer, err = NewResultWriter(out, outMaxSize*1024*1024)
+
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("out", out).
+				Fatal("Could not open results file")
+		}
+
+		run.Results = append(run.Results, writer)
+	}
 
 	status := NewStatus()
+	DefaultTimeout = timeout
+
+	latencyBuckets, err := ParseBuckets(buckets)
+
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("buckets", buckets).
+			Fatal("Could not parse buckets")
+	}
+
+	iterationDurationBuckets, err := ParseBuckets(iterationBuckets)
 
-	go InitiateRequests(concurrency, time.Duration(sleep), repeat, targets, status, closer)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("iteration-buckets", iterationBuckets).
+			Fatal("Could not parse iteration buckets")
+	}
+
+	metrics := MetricsConfig{
+		Buckets:          latencyBuckets,
+		IterationBuckets: iterationDurationBuckets,
+		Summary:          &latencySummary,
+	}
+
+	loaded := LoadTargets(concurrency, time.Duration(sleep), repeat, targets, watch)
+
+	if config, _ := loaded.Config(); config != nil {
+		ConfigureMetrics(metrics.Merge(config.Metrics))
+		InitScenarioMetrics(config)
+	} else {
+		ConfigureMetrics(metrics)
+	}
+
+	run.Go(func() {
+		InitiateRequests(ctx, run, loaded, status)
+	})
 	go InitiateServer(host, port, status)
 
-	<-closer
+	aborted := make(chan *ThresholdResult, 1)
+
+	go func() {
+		aborted <- WatchThresholds(run, loaded, results)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	AwaitRun(run, cancel, signals, duration, grace)
+
+	status.Flush()
+	cancel()
+
+	if writer != nil {
+		err := writer.Close()
+
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("out", out).
+				Error("Could not write results")
+		}
+	}
+
+	report := results.Report()
+
+	if config, _ := loaded.Config(); config != nil {
+		report.Thresholds = EvaluateThresholds(config.Thresholds, results)
+	}
+
+	if summary {
+		report.Print(os.Stdout)
+	} else {
+		report.PrintThresholds(os.Stdout)
+	}
+
+	if summaryJSON != "" {
+		err := report.WriteJSON(summaryJSON)
+
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("summary-json", summaryJSON).
+				Error("Could not write summary")
+		}
+	}
+
+	if junit != "" {
+		err := checks.Write(junit)
+
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("junit", junit).
+				Error("Could not write JUnit report")
+		}
+	}
+
+	if html != "" {
+		htmlReport := &HTMLReport{
+			Summary:  report,
+			Timeline: timeline,
+			Status:   status,
+		}
+
+		err := htmlReport.Write(html)
+
+		if err != nil {
+			logrus.
+				WithError(err).
+				WithField("html", html).
+				Error("Could not write HTML report")
+		}
+	}
+
+	if <-aborted != nil || report.Failed() {
+		logrus.
+			WithField("code", ExitThresholds).
+			Error("Run ended with failed thresholds. Closing down.")
+		os.Exit(ExitThresholds)
+	}
+
+	logrus.Info("Run ended. Closing down.")
 }
 
 func InitiateServer(host string, port int, status *Status) {
@@ -146,87 +462,59 @@ func InitiateServer(host string, port int, status *Status) {
 	httpLogger.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%d", host, port), nil))
 }
 
-func InitiateRequests(
+// LoadTargets sets the defaults of scenarios and loads the targets file,
+// which is then watched for changes
+func LoadTargets(
 	concurrency int,
 	sleep time.Duration,
 	repeat int,
 	filename string,
-	status *Status,
-	closer chan bool,
-) {
+	watch time.Duration,
+) *Targets {
 	reqLogger := logrus.
 		WithField("concurrency", concurrency).
 		WithField("sleep", sleep.String()).
 		WithField("repeat", repeat).
 		WithField("targets", filename)
 
-	reqLogger.Info("Started request loop")
+	ScenarioDefaults.Concurrency = concurrency
+	ScenarioDefaults.Sleep = int(sleep)
+	ScenarioDefaults.Repeat = repeat
 
-	requests, err := LoadRequests(filename)
+	targets := NewTargets(filename)
+	err := targets.Load()
 
 	if err != nil {
-		TargetsFileError.Inc()
 		reqLogger.
 			WithError(err).
 			Error("Error reading targets file")
 	}
 
-	RuntimeGauge.
-		WithLabelValues(
-			strconv.Itoa(len(requests)),
-			strconv.Itoa(concurrency),
-			sleep.String(),
-			strconv.Itoa(repeat),
-		).
-		SetToCurrentTime()
+	go targets.Watch(watch)
 
-	for _, r := range requests {
-		RequestStatusCounter.WithLabelValues(r.GetName(), "error")
+	return targets
+}
 
-		for _, status := range []string{"2xx", "4xx", "5xx"} {
-			RequestStatusCounter.WithLabelValues(r.GetName(), status)
-			RequestLatencySummary.WithLabelValues(r.GetName(), status)
-		}
-	}
+func InitiateRequests(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	status *Status,
+) {
+	logrus.
+		WithField("targets", targets.Filename).
+		Info("Started request loop")
 
-	for i := 0; i < concurrency; i++ {
-		go RunRequests(requests, sleep, repeat, status, closer)
-	}
+	// Until the targets file is valid, only metrics about it are served
+	RunPools(ctx, run, targets, status)
 }
 
 func RunRequests(
-	requests []*Request,
-	sleep time.Duration,
-	repeat int,
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	name string,
 	status *Status,
-	closer chan bool,
 ) {
-	collection := RequestCollection{Requests: requests}
-	runner := Runner{
-		History:  NewHistory(),
-		Requests: &collection,
-		Status:   status,
-	}
-	repeated := 0
-
-	runLogger := logrus.
-		WithField("requests", len(requests)).
-		WithField("sleep", sleep.String()).
-		WithField("repeat", repeat).
-		WithField("repeated", repeated)
-
-	for {
-		runLogger.Info("Initiated requests")
-		runner.Run()
-
-		if repeat > -1 && repeated >= repeat {
-			runLogger.Info("Number of repeats reached. Closing down.")
-			closer <- true
-			break
-		}
-
-		repeated++
-		runLogger.Info("Requests ended. Sleeping intil next run.")
-		time.Sleep(sleep * time.Second)
-	}
+	NewWorker(targets, name, status, run.Results).Work(ctx, run, nil)
 }
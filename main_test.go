@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -132,7 +133,8 @@ func TestRequests(t *testing.T) {
 	)
 
 	status := NewStatus()
-	go InitiateRequests(2, 1, -1, tmpfile.Name(), status, make(chan bool))
+	targets := LoadTargets(2, 1, -1, tmpfile.Name(), 0)
+	go InitiateRequests(context.Background(), NewRun(), targets, status)
 	go func() {
 		time.Sleep(4 * time.Second)
 		t.Error("Timeout")
@@ -155,7 +157,6 @@ func TestLimitedRepeat(t *testing.T) {
 	defer httpmock.DeactivateAndReset()
 
 	called := 0
-	wait := make(chan bool)
 
 	httpmock.RegisterResponder(
 		"GET",
@@ -174,16 +175,18 @@ func TestLimitedRepeat(t *testing.T) {
 		},
 	}
 
-	go func() {
-		time.Sleep(4 * time.Second)
-		t.Error("Timeout")
-		close(wait)
-	}()
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{
+		&Scenario{Name: "limited", Sleep: 0, Repeat: 2, Requests: requests},
+	}})
 
 	status := NewStatus()
-	go RunRequests(requests, 0, 2, status, wait)
+	run := NewRun()
+	run.Go(func() { RunRequests(context.Background(), run, targets, "limited", status) })
 
-	<-wait
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
 
 	if called != 3 {
 		t.Errorf("Repeated %d times instead of 3", called)
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the request
+// latency histogram, unless set by flags or the targets file
+var DefaultLatencyBuckets = prometheus.DefBuckets
+
+// DefaultIterationBuckets are the upper bounds in seconds of the iteration
+// duration histogram, unless set by flags or the targets file
+var DefaultIterationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// LatencySummaryEnabled tells whether request latencies are also exported
+// as a summary, which can't be aggregated across instances
+var LatencySummaryEnabled = true
+
+// MetricsConfig sets how latencies are exported. The targets file overrides
+// the flags, and it's only read at start.
+type MetricsConfig struct {
+	Buckets          []float64 `yaml:"buckets"`
+	IterationBuckets []float64 `yaml:"iteration_buckets"`
+	Summary          *bool     `yaml:"summary"`
+}
+
+// Merge returns the config with the values set in other
+func (m MetricsConfig) Merge(other *MetricsConfig) MetricsConfig {
+	if other == nil {
+		return m
+	}
+
+	if other.Buckets != nil {
+		m.Buckets = other.Buckets
+	}
+
+	if other.IterationBuckets != nil {
+		m.IterationBuckets = other.IterationBuckets
+	}
+
+	if other.Summary != nil {
+		m.Summary = other.Summary
+	}
+
+	return m
+}
+
+func (m *MetricsConfig) Validate() error {
+	err := ValidateBuckets(m.Buckets)
+
+	if err != nil {
+		return fmt.Errorf("Metrics buckets: %s", err)
+	}
+
+	err = ValidateBuckets(m.IterationBuckets)
+
+	if err != nil {
+		return fmt.Errorf("Metrics iteration_buckets: %s", err)
+	}
+
+	return nil
+}
+
+// ConfigureMetrics replaces the histograms with ones using the buckets of
+// the config, and unregisters the latency summary if it's disabled. It must
+// be called before any requests are sent.
+func ConfigureMetrics(m MetricsConfig) {
+	if m.Buckets != nil {
+		prometheus.Unregister(RequestLatencyHistogram)
+		RequestLatencyHistogram = newRequestLatencyHistogram(m.Buckets)
+		prometheus.MustRegister(RequestLatencyHistogram)
+
+		prometheus.Unregister(RequestPhaseHistogram)
+		RequestPhaseHistogram = newRequestPhaseHistogram(m.Buckets)
+		prometheus.MustRegister(RequestPhaseHistogram)
+	}
+
+	if m.IterationBuckets != nil {
+		prometheus.Unregister(IterationDurationHistogram)
+		IterationDurationHistogram = newIterationDurationHistogram(m.IterationBuckets)
+		prometheus.MustRegister(IterationDurationHistogram)
+	}
+
+	if m.Summary != nil && *m.Summary != LatencySummaryEnabled {
+		LatencySummaryEnabled = *m.Summary
+
+		if LatencySummaryEnabled {
+			prometheus.MustRegister(RequestLatencySummary)
+		} else {
+			prometheus.Unregister(RequestLatencySummary)
+		}
+	}
+}
+
+// ParseBuckets parses comma separated upper bounds in seconds, like
+// 0.1,0.5,1. Empty returns nil.
+func ParseBuckets(value string) ([]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var buckets []float64
+
+	for _, field := range strings.Split(value, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Bucket must be a number of seconds, not %q", field)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, ValidateBuckets(buckets)
+}
+
+// ValidateBuckets checks that buckets are positive and increasing
+func ValidateBuckets(buckets []float64) error {
+	if buckets != nil && len(buckets) == 0 {
+		return errors.New("Buckets can't be empty")
+	}
+
+	for i, bucket := range buckets {
+		if bucket <= 0 {
+			return fmt.Errorf("Bucket must be positive, not %v", bucket)
+		}
+
+		if i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("Buckets must be increasing, but %v follows %v", bucket, buckets[i-1])
+		}
+	}
+
+	return nil
+}
+
+func newRequestLatencyHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goload_request_duration_seconds",
+			Help:    "Goload http request latency in seconds",
+			Buckets: buckets,
+		},
+		[]string{"scenario", "name", "status"},
+	)
+}
+
+func newRequestPhaseHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goload_request_phase_duration_seconds",
+			Help:    "Goload http request latency in seconds by phase - dns, connect, tls, ttfb and transfer",
+			Buckets: buckets,
+		},
+		[]string{"scenario", "name", "phase"},
+	)
+}
+
+func newIterationDurationHistogram(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goload_iteration_duration_seconds",
+			Help:    "Goload duration of iterations of scenarios in seconds, including think time",
+			Buckets: buckets,
+		},
+		[]string{"scenario"},
+	)
+}
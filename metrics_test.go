@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseBuckets(t *testing.T) {
+	buckets, err := ParseBuckets("0.05, 0.1,1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(buckets, []float64{0.05, 0.1, 1}) {
+		t.Errorf("Buckets were not parsed: %v", buckets)
+	}
+
+	if buckets, err := ParseBuckets(""); buckets != nil || err != nil {
+		t.Errorf("Empty buckets were not nil: %v %s", buckets, err)
+	}
+
+	for _, value := range []string{"0.1,fast", "0.1,0.1", "1,0.5", "0,1"} {
+		if _, err := ParseBuckets(value); err == nil {
+			t.Errorf("Parsed invalid buckets %q", value)
+		}
+	}
+}
+
+func TestMetricsConfigMerge(t *testing.T) {
+	disabled := false
+	flags := MetricsConfig{Buckets: []float64{1, 2}, IterationBuckets: []float64{10}}
+	merged := flags.Merge(&MetricsConfig{Buckets: []float64{0.5}, Summary: &disabled})
+
+	if !reflect.DeepEqual(merged.Buckets, []float64{0.5}) ||
+		!reflect.DeepEqual(merged.IterationBuckets, []float64{10}) ||
+		merged.Summary != &disabled {
+		t.Errorf("Targets file did not override flags: %+v", merged)
+	}
+
+	if !reflect.DeepEqual(flags.Merge(nil), flags) {
+		t.Error("Flags were not kept without a targets file config")
+	}
+}
+
+func TestConfigureMetrics(t *testing.T) {
+	histogram := RequestLatencyHistogram
+	enabled := true
+	disabled := false
+
+	defer func() {
+		ConfigureMetrics(MetricsConfig{Buckets: DefaultLatencyBuckets, Summary: &enabled})
+	}()
+
+	ConfigureMetrics(MetricsConfig{Buckets: []float64{0.5, 1}, Summary: &disabled})
+
+	if RequestLatencyHistogram == histogram {
+		t.Fatal("Histogram was not replaced")
+	}
+
+	if LatencySummaryEnabled || prometheus.Unregister(RequestLatencySummary) {
+		t.Error("Summary was not unregistered")
+	}
+
+	RequestLatencyHistogram.WithLabelValues("default", "start", "2xx").Observe(0.7)
+
+	var metric dto.Metric
+
+	RequestLatencyHistogram.WithLabelValues("default", "start", "2xx").(prometheus.Histogram).Write(&metric)
+
+	buckets := metric.GetHistogram().GetBucket()
+
+	if len(buckets) != 2 || buckets[0].GetCumulativeCount() != 0 || buckets[1].GetCumulativeCount() != 1 {
+		t.Errorf("Histogram did not use the buckets: %v", buckets)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ResultsBuffer is the number of results waiting to be written before
+// further results are dropped, rather than blocking workers
+const ResultsBuffer = 10000
+
+// ResultWriter writes every result as a line of json to a file, in the
+// background. Once the file reaches MaxSize bytes, it's rotated to
+// name.1.jsonl, name.2.jsonl and so on, oldest first.
+type ResultWriter struct {
+	Filename string
+	MaxSize  int64
+	results  chan *Result
+	done     chan struct{}
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	rotated  int
+	dropped  int64
+}
+
+func NewResultWriter(filename string, maxSize int64) (*ResultWriter, error) {
+	w := &ResultWriter{
+		Filename: filename,
+		MaxSize:  maxSize,
+		results:  make(chan *Result, ResultsBuffer),
+		done:     make(chan struct{}),
+	}
+
+	err := w.open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	go w.loop()
+
+	return w, nil
+}
+
+// Handle queues the result to be written, or drops it if the queue is full
+func (w *ResultWriter) Handle(result *Result) {
+	select {
+	case w.results <- result:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+		ResultsDroppedError.Inc()
+	}
+}
+
+// Close writes the queued results and closes the file. Results must not be
+// handled after it's closed.
+func (w *ResultWriter) Close() error {
+	close(w.results)
+	<-w.done
+
+	if dropped := atomic.LoadInt64(&w.dropped); dropped > 0 {
+		logrus.
+			WithField("out", w.Filename).
+			WithField("dropped", dropped).
+			Warn("Results were dropped since they could not be written fast enough")
+	}
+
+	err := w.writer.Flush()
+
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+func (w *ResultWriter) loop() {
+	defer close(w.done)
+
+	for result := range w.results {
+		err := w.write(result)
+
+		if err != nil {
+			ResultsWriteError.Inc()
+			logrus.
+				WithError(err).
+				WithField("out", w.Filename).
+				Error("Could not write result")
+		}
+
+		// Flush whenever the queue is drained, to keep the file current
+		// without flushing every line
+		if len(w.results) == 0 {
+			w.writer.Flush()
+		}
+	}
+}
+
+func (w *ResultWriter) write(result *Result) error {
+	data, err := json.Marshal(result)
+
+	if err != nil {
+		return err
+	}
+
+	if w.MaxSize > 0 && w.size > 0 && w.size+int64(len(data))+1 > w.MaxSize {
+		err = w.rotate()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := w.writer.Write(append(data, '\n'))
+	w.size += int64(n)
+
+	return err
+}
+
+func (w *ResultWriter) open() error {
+	file, err := os.OpenFile(w.Filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.size = 0
+
+	return nil
+}
+
+func (w *ResultWriter) rotate() error {
+	err := w.writer.Flush()
+
+	if err == nil {
+		err = w.file.Close()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	w.rotated++
+
+	err = os.Rename(w.Filename, rotatedFilename(w.Filename, w.rotated))
+
+	if err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+// rotatedFilename returns the name of the nth rotated file, like
+// results.1.jsonl for results.jsonl
+func rotatedFilename(filename string, n int) string {
+	ext := filepath.Ext(filename)
+
+	return fmt.Sprintf("%s.%d%s", strings.TrimSuffix(filename, ext), n, ext)
+}
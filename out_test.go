@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResultWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "results.jsonl")
+	writer, err := NewResultWriter(filename, 500)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		writer.Handle(&Result{
+			Scenario:  "browse",
+			Worker:    1,
+			Iteration: i,
+			Name:      "start",
+			Method:    "GET",
+			URL:       "http://some-host/",
+			Status:    "2xx",
+			Checks:    []*Check{{Part: "status_code", Expected: "2..", Passed: true}},
+		})
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	iteration := 0
+
+	for _, name := range []string{"results.1.jsonl", "results.2.jsonl", "results.jsonl"} {
+		file, err := os.Open(filepath.Join(dir, name))
+
+		if err != nil {
+			t.Fatalf("Results were not rotated: %s", err)
+		}
+
+		info, _ := file.Stat()
+
+		if info.Size() > 500 {
+			t.Errorf("Rotated file %s was larger than the max size: %d", name, info.Size())
+		}
+
+		scanner := bufio.NewScanner(file)
+
+		for scanner.Scan() {
+			var result Result
+
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Iteration != iteration || len(result.Checks) != 1 {
+				t.Errorf("Result %d was not written in order: %s", iteration, scanner.Bytes())
+			}
+
+			iteration++
+		}
+
+		file.Close()
+	}
+
+	if iteration != 5 {
+		t.Errorf("Expected 5 results, got %d", iteration)
+	}
+}
+
+func TestResultWriterDrops(t *testing.T) {
+	before := counterValue(ResultsDroppedError)
+	writer := &ResultWriter{results: make(chan *Result)}
+
+	writer.Handle(&Result{})
+
+	if writer.dropped != 1 || counterValue(ResultsDroppedError) != before+1 {
+		t.Error("Result was not dropped when the queue was full")
+	}
+}
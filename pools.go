@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PoolInterval is how often pools check the targets for changes
+var PoolInterval = 100 * time.Millisecond
+
+// Pool runs the workers of a scenario, in one of the modes of Mode, until
+// it's stopped or every worker is done
+type Pool struct {
+	Name string
+	Mode string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// PoolMode returns how the workers of the scenario are run. Pools are
+// restarted when it changes. Arrival rate pools have a fixed number of
+// workers, while the others follow the scenario as it changes.
+func PoolMode(scenario *Scenario) string {
+	switch {
+	case scenario.Rate != "":
+		return "rate/" + strconv.Itoa(scenario.Concurrency)
+	case len(scenario.Stages) > 0:
+		return "stages"
+	}
+
+	return "concurrency"
+}
+
+func StartPool(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	scenario *Scenario,
+	status *Status,
+) *Pool {
+	p := &Pool{
+		Name: scenario.Name,
+		Mode: PoolMode(scenario),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	run.Go(func() {
+		defer close(p.done)
+
+		switch {
+		case scenario.Rate != "":
+			RunArrivals(ctx, run, targets, p.Name, status, p.stop)
+		case len(scenario.Stages) > 0:
+			RunStages(ctx, run, targets, p.Name, status, p.stop)
+		default:
+			RunWorkers(ctx, run, targets, p.Name, status, p.stop)
+		}
+	})
+
+	return p
+}
+
+// Stop stops the workers of the pool after their current iteration
+func (p *Pool) Stop() {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+}
+
+// Done is closed when every worker of the pool is done
+func (p *Pool) Done() <-chan struct{} {
+	return p.done
+}
+
+// RunPools starts a pool for every scenario of the targets, and keeps them
+// in line with the targets as they're reloaded. Added scenarios are
+// started, removed ones stopped, and pools of scenarios changing mode are
+// restarted. Scenarios that are done aren't restarted. Until the targets
+// are valid, nothing is started. It returns when the run is stopped, or
+// when every pool is done.
+func RunPools(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	status *Status,
+) {
+	ticker := time.NewTicker(PoolInterval)
+	defer ticker.Stop()
+
+	pools := make(map[string]*Pool)
+	version := 0
+
+	for {
+		config, current := targets.Config()
+
+		if config != nil && current != version {
+			version = current
+			UpdatePools(ctx, run, targets, config, status, pools)
+		}
+
+		if version > 0 && poolsDone(pools) {
+			logrus.Info("Every scenario is done")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-run.Stopping():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UpdatePools starts, stops and restarts pools to match config
+func UpdatePools(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	config *Config,
+	status *Status,
+	pools map[string]*Pool,
+) {
+	RuntimeGauge.Reset()
+
+	for _, scenario := range config.Scenarios {
+		RuntimeGauge.
+			WithLabelValues(
+				scenario.Name,
+				strconv.Itoa(len(scenario.Requests)),
+				strconv.Itoa(scenario.Concurrency),
+				strconv.Itoa(scenario.Sleep),
+				strconv.Itoa(scenario.Repeat),
+			).
+			SetToCurrentTime()
+
+		logger := logrus.
+			WithField("scenario", scenario.Name).
+			WithField("mode", PoolMode(scenario))
+
+		pool, ok := pools[scenario.Name]
+
+		if ok && pool.Mode == PoolMode(scenario) {
+			continue
+		}
+
+		if ok {
+			select {
+			case <-pool.Done():
+				continue
+			default:
+			}
+
+			logger.Info("Scenario changed mode. Restarting workers.")
+			pool.Stop()
+		} else {
+			logger.Info("Starting scenario")
+		}
+
+		pools[scenario.Name] = StartPool(ctx, run, targets, scenario, status)
+	}
+
+	for name, pool := range pools {
+		if config.Scenario(name) == nil {
+			logrus.
+				WithField("scenario", name).
+				Info("Scenario was removed from targets. Stopping workers.")
+			pool.Stop()
+			delete(pools, name)
+		}
+	}
+}
+
+func poolsDone(pools map[string]*Pool) bool {
+	for _, pool := range pools {
+		select {
+		case <-pool.Done():
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// RunWorkers keeps as many workers running as the concurrency of a
+// scenario. Stopped workers finish their current iteration first. It's done
+// when every worker is done, like when the scenario has repeated enough.
+func RunWorkers(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	name string,
+	status *Status,
+	stop <-chan struct{},
+) {
+	logger := logrus.WithField("scenario", name)
+	ticker := time.NewTicker(PoolInterval)
+	defer ticker.Stop()
+
+	var workers []chan struct{}
+	var running int64
+
+loop:
+	for {
+		config, _ := targets.Config()
+		scenario := config.Scenario(name)
+
+		if scenario == nil {
+			logger.Warn("Scenario was removed from targets. Closing down.")
+			break
+		}
+
+		for len(workers) < scenario.Concurrency {
+			worker := make(chan struct{})
+			workers = append(workers, worker)
+			atomic.AddInt64(&running, 1)
+
+			run.Go(func() {
+				defer atomic.AddInt64(&running, -1)
+				NewWorker(targets, name, status, run.Results).Work(ctx, run, worker)
+			})
+		}
+
+		for len(workers) > scenario.Concurrency {
+			close(workers[len(workers)-1])
+			workers = workers[:len(workers)-1]
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-run.Stopping():
+			break loop
+		case <-stop:
+			break loop
+		case <-ticker.C:
+		}
+
+		if atomic.LoadInt64(&running) == 0 {
+			logger.Info("Every worker is done. Closing down.")
+			break
+		}
+	}
+
+	for _, worker := range workers {
+		close(worker)
+	}
+}
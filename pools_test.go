@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolMode(t *testing.T) {
+	for mode, scenario := range map[string]*Scenario{
+		"concurrency": {Concurrency: 2},
+		"stages":      {Concurrency: 2, Stages: []*Stage{{Duration: time.Second, Target: 1}}},
+		"rate/2":      {Concurrency: 2, Rate: "10/s"},
+	} {
+		if PoolMode(scenario) != mode {
+			t.Errorf("Expected mode %s, got %s", mode, PoolMode(scenario))
+		}
+	}
+}
+
+func TestRunPools(t *testing.T) {
+	PoolInterval = 10 * time.Millisecond
+	defer func() { PoolInterval = 100 * time.Millisecond }()
+
+	var called int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&called, 1)
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	scenario := func(name string, concurrency int) *Scenario {
+		return &Scenario{
+			Name:        name,
+			Concurrency: concurrency,
+			Repeat:      -1,
+			Requests:    []*Request{{Name: "index", Method: "GET", URL: server.URL}},
+		}
+	}
+
+	first := ActiveVirtualUsersGauge.WithLabelValues("pooled-first")
+	added := ActiveVirtualUsersGauge.WithLabelValues("pooled-added")
+
+	// The targets file is invalid at start
+	targets := NewTargets("")
+	run := NewRun()
+
+	run.Go(func() { RunPools(context.Background(), run, targets, NewStatus()) })
+
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&called) != 0 {
+		t.Error("Requests were sent without targets")
+	}
+
+	targets.Set(&Config{Scenarios: []*Scenario{scenario("pooled-first", 1)}})
+	time.Sleep(100 * time.Millisecond)
+
+	if gaugeValue(first) != 1 {
+		t.Errorf("Expected 1 worker once the targets were valid, got %f", gaugeValue(first))
+	}
+
+	targets.Set(&Config{Scenarios: []*Scenario{scenario("pooled-first", 3), scenario("pooled-added", 2)}})
+	time.Sleep(100 * time.Millisecond)
+
+	if gaugeValue(first) != 3 || gaugeValue(added) != 2 {
+		t.Errorf("Expected 3 and 2 workers after reload, got %f and %f", gaugeValue(first), gaugeValue(added))
+	}
+
+	targets.Set(&Config{Scenarios: []*Scenario{scenario("pooled-added", 1)}})
+	time.Sleep(100 * time.Millisecond)
+
+	if gaugeValue(first) != 0 || gaugeValue(added) != 1 {
+		t.Errorf("Expected 0 and 1 workers after reload, got %f and %f", gaugeValue(first), gaugeValue(added))
+	}
+
+	run.Stop()
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
+
+	if gaugeValue(added) != 0 {
+		t.Errorf("Workers were still active after the run stopped: %f", gaugeValue(added))
+	}
+}
+
+func TestRunPoolsModeChange(t *testing.T) {
+	PoolInterval = 10 * time.Millisecond
+	defer func() { PoolInterval = 100 * time.Millisecond }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	requests := func() []*Request {
+		return []*Request{{Name: "index", Method: "GET", URL: server.URL}}
+	}
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{
+		{Name: "pooled-mode", Concurrency: 1, Repeat: -1, Sleep: 1, Requests: requests()},
+	}})
+
+	run := NewRun()
+	run.Go(func() { RunPools(context.Background(), run, targets, NewStatus()) })
+
+	time.Sleep(50 * time.Millisecond)
+
+	targets.Set(&Config{Scenarios: []*Scenario{
+		{Name: "pooled-mode", Concurrency: 1, Repeat: -1, Rate: "100/s", Requests: requests()},
+	}})
+	time.Sleep(100 * time.Millisecond)
+
+	if gaugeValue(IterationsTargetRateGauge.WithLabelValues("pooled-mode")) != 100 {
+		t.Error("Scenario was not restarted with an arrival rate")
+	}
+
+	run.Stop()
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
+}
+
+func TestRunPoolsDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{{
+		Name:        "pooled-done",
+		Concurrency: 2,
+		Repeat:      1,
+		Requests:    []*Request{{Name: "index", Method: "GET", URL: server.URL}},
+	}}})
+
+	run := NewRun()
+	run.Go(func() { RunPools(context.Background(), run, targets, NewStatus()) })
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Every scenario was done, but the run was not")
+	}
+}
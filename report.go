@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// ReportPoints is the most points in time shown in the charts of the
+	// HTML report. Intervals of longer runs are merged.
+	ReportPoints = 120
+
+	// ReportSampleLength is how much of a sampled response is shown in the
+	// HTML report
+	ReportSampleLength = 1000
+
+	reportWidth   = 720
+	reportHeight  = 220
+	reportPadding = 60
+)
+
+var reportColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// HTMLReport is a single static HTML file with the summary of a run, charts
+// of how it went over time and samples of its responses
+type HTMLReport struct {
+	Summary  *SummaryReport
+	Timeline *Timeline
+	Status   *Status
+}
+
+// Write writes the report to filename
+func (h *HTMLReport) Write(filename string) error {
+	var buffer bytes.Buffer
+
+	err := h.Render(&buffer)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, buffer.Bytes(), 0644)
+}
+
+func (h *HTMLReport) Render(writer io.Writer) error {
+	requests := h.Timeline.Sorted()
+	size := 1
+
+	for _, request := range requests {
+		if n := (len(request.Buckets) + ReportPoints - 1) / ReportPoints; n > size {
+			size = n
+		}
+	}
+
+	interval := h.Timeline.Interval * time.Duration(size)
+	data := reportData{
+		Generated: time.Now().Format(time.RFC1123),
+		Summary:   h.Summary,
+	}
+
+	var throughput []reportSeries
+
+	for i, request := range requests {
+		buckets := request.Merged(size)
+		color := reportColors[i%len(reportColors)]
+		rates := make([]float64, len(buckets))
+		p50 := make([]float64, len(buckets))
+		p95 := make([]float64, len(buckets))
+		p99 := make([]float64, len(buckets))
+
+		for j, bucket := range buckets {
+			latency := bucket.Latency()
+			rates[j] = float64(bucket.Count) / interval.Seconds()
+			p50[j] = latency.P50
+			p95[j] = latency.P95
+			p99[j] = latency.P99
+		}
+
+		label := request.Scenario + " " + request.Name
+		throughput = append(throughput, reportSeries{label, color, rates})
+
+		slowest, failed := h.Status.Samples(request.Scenario, request.Name)
+
+		data.Requests = append(data.Requests, &reportRequest{
+			Scenario: request.Scenario,
+			Name:     request.Name,
+			Summary:  h.summary(request),
+			Latency: lineChart(interval, formatSeconds, []reportSeries{
+				{"p50", reportColors[0], p50},
+				{"p95", reportColors[1], p95},
+				{"p99", reportColors[3], p99},
+			}),
+			Histogram: histogramChart(request.Histogram),
+			Slowest:   slowest,
+			Failed:    failed,
+		})
+	}
+
+	data.Throughput = lineChart(interval, formatRate, throughput)
+
+	return reportTemplate.Execute(writer, data)
+}
+
+func (h *HTMLReport) summary(request *RequestTimeline) *RequestSummary {
+	for _, summary := range h.Summary.Requests {
+		if summary.Scenario == request.Scenario && summary.Name == request.Name {
+			return summary
+		}
+	}
+
+	return nil
+}
+
+type reportData struct {
+	Generated  string
+	Summary    *SummaryReport
+	Throughput template.HTML
+	Requests   []*reportRequest
+}
+
+type reportRequest struct {
+	Scenario  string
+	Name      string
+	Summary   *RequestSummary
+	Latency   template.HTML
+	Histogram template.HTML
+	Slowest   []*StatusEntry
+	Failed    []*StatusEntry
+}
+
+type reportSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+// lineChart draws an SVG chart of series of values, one per interval
+func lineChart(interval time.Duration, format func(float64) string, series []reportSeries) template.HTML {
+	points := 0
+	top := 0.0
+
+	for _, s := range series {
+		if len(s.Values) > points {
+			points = len(s.Values)
+		}
+
+		for _, value := range s.Values {
+			top = math.Max(top, value)
+		}
+	}
+
+	if points == 0 {
+		return ""
+	}
+
+	if top == 0 {
+		top = 1
+	}
+
+	width := float64(reportWidth - reportPadding - 20)
+	height := float64(reportHeight - reportPadding/2)
+	step := width / math.Max(float64(points-1), 1)
+
+	var svg strings.Builder
+
+	fmt.Fprintf(&svg, `<svg viewBox="0 0 %d %d" class="chart">`, reportWidth, reportHeight+20*len(series))
+	reportAxes(&svg, top, format, func(i int) string {
+		return (interval * time.Duration(i*(points-1)/4)).String()
+	})
+
+	for i, s := range series {
+		var coordinates []string
+
+		for j, value := range s.Values {
+			coordinates = append(coordinates, fmt.Sprintf(
+				"%.1f,%.1f",
+				float64(reportPadding)+float64(j)*step,
+				height-value/top*(height-10),
+			))
+		}
+
+		fmt.Fprintf(
+			&svg,
+			`<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`,
+			s.Color,
+			strings.Join(coordinates, " "),
+		)
+		fmt.Fprintf(
+			&svg,
+			`<rect x="%d" y="%d" width="10" height="10" fill="%s"/><text x="%d" y="%d">%s</text>`,
+			reportPadding, reportHeight+20*i, s.Color,
+			reportPadding+15, reportHeight+20*i+9, template.HTMLEscapeString(s.Name),
+		)
+	}
+
+	svg.WriteString("</svg>")
+
+	return template.HTML(svg.String())
+}
+
+// histogramChart draws an SVG bar chart of the counts of a latency histogram
+func histogramChart(counts []int) template.HTML {
+	top := 1
+
+	for _, count := range counts {
+		if count > top {
+			top = count
+		}
+	}
+
+	width := float64(reportWidth-reportPadding) / float64(len(counts))
+	height := float64(reportHeight - reportPadding/2)
+
+	var svg strings.Builder
+
+	fmt.Fprintf(&svg, `<svg viewBox="0 0 %d %d" class="chart">`, reportWidth, reportHeight)
+	reportAxes(&svg, float64(top), func(value float64) string {
+		return fmt.Sprintf("%.0f", value)
+	}, func(i int) string {
+		return ""
+	})
+
+	for i, count := range counts {
+		bar := float64(count) / float64(top) * (height - 10)
+		label := "+Inf"
+
+		if i < len(TimelineLatencyBuckets) {
+			label = "≤" + formatSeconds(TimelineLatencyBuckets[i])
+		}
+
+		fmt.Fprintf(
+			&svg,
+			`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %d</title></rect>`,
+			float64(reportPadding)+float64(i)*width+1, height-bar, width-2, bar, reportColors[0],
+			label, count,
+		)
+		fmt.Fprintf(
+			&svg,
+			`<text x="%.1f" y="%.1f" text-anchor="middle" class="small">%s</text>`,
+			float64(reportPadding)+(float64(i)+0.5)*width, height+14, label,
+		)
+	}
+
+	svg.WriteString("</svg>")
+
+	return template.HTML(svg.String())
+}
+
+// reportAxes draws the grid lines of a chart, labelled by their value and
+// their position in time
+func reportAxes(svg *strings.Builder, top float64, format func(float64) string, at func(int) string) {
+	height := float64(reportHeight - reportPadding/2)
+
+	for i := 0; i <= 4; i++ {
+		y := height - float64(i)/4*(height-10)
+
+		fmt.Fprintf(
+			svg,
+			`<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/><text x="%d" y="%.1f" text-anchor="end">%s</text>`,
+			reportPadding, y, reportWidth, y,
+			reportPadding-5, y+4, template.HTMLEscapeString(format(top*float64(i)/4)),
+		)
+
+		if label := at(i); label != "" {
+			fmt.Fprintf(
+				svg,
+				`<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`,
+				float64(reportPadding)+float64(i)/4*float64(reportWidth-reportPadding-20), height+16, label,
+			)
+		}
+	}
+}
+
+func formatRate(value float64) string {
+	return fmt.Sprintf("%.1f/s", value)
+}
+
+// formatSample returns a response as json, cut at ReportSampleLength
+func formatSample(response interface{}) string {
+	if text, ok := response.(string); ok {
+		return truncate(text, ReportSampleLength)
+	}
+
+	data, err := json.MarshalIndent(response, "", "  ")
+
+	if err != nil {
+		return fmt.Sprint(response)
+	}
+
+	return truncate(string(data), ReportSampleLength)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": formatSeconds,
+	"sample":  formatSample,
+	"errors":  sortedErrors,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goload report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.text { text-align: left; }
+.chart { width: 100%; max-width: 900px; font-size: 11px; }
+.chart .grid { stroke: #eee; }
+.chart .small { font-size: 9px; }
+.failed { color: #d62728; }
+pre { background: #f6f6f6; padding: 0.5em; max-height: 20em; overflow: auto; }
+section { border-top: 1px solid #ccc; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>goload report</h1>
+<p>Generated {{ .Generated }}, after a run of {{ seconds .Summary.Duration }}.</p>
+{{ with .Summary.Thresholds }}
+<h2>Thresholds</h2>
+<table>
+<tr><th>Threshold</th><th>Outcome</th><th>Value</th></tr>
+{{ range . }}<tr><td>{{ .Threshold }}</td><td{{ if not .Passed }} class="failed"{{ end }}>{{ if .Passed }}ok{{ else }}FAIL{{ end }}</td><td class="text">{{ .Message }}</td></tr>
+{{ end }}</table>
+{{ end }}
+<h2>Requests</h2>
+<table>
+<tr><th>Scenario</th><th>Name</th><th>Requests</th><th>2xx</th><th>4xx</th><th>5xx</th><th>Error</th><th>Timeout</th><th>Expect ok</th><th>Expect failed</th><th>Min</th><th>Avg</th><th>P50</th><th>P90</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{ range .Summary.Requests }}<tr><td>{{ .Scenario }}</td><td class="text">{{ .Name }}</td><td>{{ .Count }}</td><td>{{ index .Statuses "2xx" }}</td><td>{{ index .Statuses "4xx" }}</td><td>{{ index .Statuses "5xx" }}</td><td>{{ index .Statuses "error" }}</td><td>{{ index .Statuses "timeout" }}</td><td>{{ .Expectations.Passed }}</td><td>{{ .Expectations.Failed }}</td><td>{{ seconds .Latency.Min }}</td><td>{{ seconds .Latency.Avg }}</td><td>{{ seconds .Latency.P50 }}</td><td>{{ seconds .Latency.P90 }}</td><td>{{ seconds .Latency.P95 }}</td><td>{{ seconds .Latency.P99 }}</td><td>{{ seconds .Latency.Max }}</td></tr>
+{{ end }}</table>
+<h2>Throughput</h2>
+{{ .Throughput }}
+{{ range .Requests }}
+<section>
+<h2>{{ .Scenario }} {{ .Name }}</h2>
+<h3>Latency percentiles</h3>
+{{ .Latency }}
+<h3>Latency histogram</h3>
+{{ .Histogram }}
+{{ with .Summary }}{{ if .Errors }}
+<h3>Errors</h3>
+<table>
+<tr><th>Count</th><th>Error</th></tr>
+{{ $errors := .Errors }}{{ range errors .Errors }}<tr><td>{{ index $errors . }}</td><td class="text">{{ . }}</td></tr>
+{{ end }}</table>
+{{ end }}{{ end }}
+{{ with .Slowest }}
+<h3>Slowest responses</h3>
+{{ range . }}<p>{{ with .Item }}item {{ . }}, {{ end }}{{ seconds .Latency }}, status {{ .Status }}{{ with .Timings }}: dns {{ seconds .DNS }}, connect {{ seconds .Connect }}, tls {{ seconds .TLS }}, ttfb {{ seconds .TTFB }}, transfer {{ seconds .Transfer }}{{ if .Reused }}, reused connection{{ end }}{{ end }}</p>
+<pre>{{ sample .Response }}</pre>
+{{ end }}{{ end }}
+{{ with .Failed }}
+<h3>Failed responses</h3>
+{{ range . }}<p class="failed">{{ with .Item }}item {{ . }}: {{ end }}{{ .Error }}{{ if .Status }}, status {{ .Status }}{{ end }}</p>
+<pre>{{ sample .Response }}</pre>
+{{ end }}{{ end }}
+</section>
+{{ end }}
+</body>
+</html>
+`))
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLReport(t *testing.T) {
+	summary := NewSummary()
+	timeline := NewTimeline(time.Second)
+	status := NewStatus()
+
+	for i := 0; i < 300; i++ {
+		result := &Result{
+			Time:     timeline.Started.Add(time.Duration(i) * time.Second),
+			Scenario: "browse",
+			Name:     "start",
+			Status:   "2xx",
+			Latency:  0.1,
+		}
+
+		summary.Handle(result)
+		timeline.Handle(result)
+	}
+
+	status.Record("browse", "start", -1, 0.3, 200, `{"slow":true}`, nil, nil)
+	status.Record("browse", "start", -1, 0, 0, "", nil, errors.New("Failed <badly>"))
+	status.Flush()
+
+	report := summary.Report()
+	report.Thresholds = EvaluateThresholds([]*Threshold{{Check: "p95 < 50ms"}}, summary)
+
+	var out bytes.Buffer
+
+	err := (&HTMLReport{Summary: report, Timeline: timeline, Status: status}).Render(&out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := out.String()
+
+	for _, expected := range []string{
+		"<h2>browse start</h2>",
+		"p95 &lt; 50ms",
+		"FAIL",
+		"<polyline",
+		"&#34;slow&#34;: true",
+		"Failed &lt;badly&gt;",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Report did not contain %s", expected)
+		}
+	}
+
+	// 300 seconds are merged into 100 points of 3 seconds
+	if points := strings.Count(strings.SplitN(html, "<polyline", 2)[1], ","); points < 100 {
+		t.Errorf("Report did not contain every point: %d", points)
+	}
+}
+
+func TestHTMLReportForeach(t *testing.T) {
+	summary := NewSummary()
+	timeline := NewTimeline(time.Second)
+	status := NewStatus()
+
+	for i := 0; i < 2; i++ {
+		item := i
+		result := &Result{
+			Time:     timeline.Started,
+			Scenario: "cleanup",
+			Name:     "remove",
+			Item:     &item,
+			Status:   "2xx",
+			Latency:  0.1,
+		}
+
+		summary.Handle(result)
+		timeline.Handle(result)
+	}
+
+	status.Record("cleanup", "remove", 0, 0.2, 200, `{"removed":"a"}`, nil, nil)
+	status.Record("cleanup", "remove", 1, 0, 0, "", nil, errors.New("Not removed"))
+	status.Flush()
+
+	var out bytes.Buffer
+
+	err := (&HTMLReport{Summary: summary.Report(), Timeline: timeline, Status: status}).Render(&out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := out.String()
+
+	for _, expected := range []string{
+		"<h2>cleanup remove</h2>",
+		"item 0, ",
+		"&#34;removed&#34;: &#34;a&#34;",
+		"item 1: Not removed",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Report did not contain %s", expected)
+		}
+	}
+}
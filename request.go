@@ -2,12 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
 )
 
@@ -65,25 +70,46 @@ func (r *RequestCollection) Current() RequestHandler {
 
 type RequestHandler interface {
 	SetParser(HistoryHandler)
+	SetSession(*Session)
+	GetScenario() string
 	GetName() string
 	GetUrl() string
 	GetMethod() string
 	GetBody() string
 	GetHeader(key string) string
-	Send() (Response, error)
+	GetOnFailure() string
+	GetWhen() string
+	GetForeach() ([]string, bool)
+	GetThinkTime() time.Duration
+	Send(ctx context.Context) (Response, error)
 }
 
 var requestHandler RequestHandler = &Request{}
 
 type Request struct {
-	Name    string            `yaml:"name"`
-	URL     string            `yaml:"url"`
-	Params  map[string]string `yaml:"params"`
-	Method  string            `yaml:"method"`
-	Body    string            `yaml:"body"`
-	Headers map[string]string `yaml:"headers"`
-	Expect  Expected          `yaml:"expect"`
-	Parser  HistoryHandler
+	Scenario  string            `yaml:"-"`
+	Name      string            `yaml:"name"`
+	URL       string            `yaml:"url"`
+	Params    map[string]string `yaml:"params"`
+	Method    string            `yaml:"method"`
+	Body      string            `yaml:"body"`
+	Headers   map[string]string `yaml:"headers"`
+	Expect    Expected          `yaml:"expect"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	Client    *ClientConfig     `yaml:"client"`
+	Retry     *RetryPolicy      `yaml:"retry"`
+	OnFailure string            `yaml:"on_failure"`
+	When      string            `yaml:"when"`
+	Foreach   *Foreach          `yaml:"foreach"`
+	ThinkTime *ThinkTime        `yaml:"think_time"`
+	Parser    HistoryHandler    `yaml:"-"`
+	Session   *Session          `yaml:"-"`
+
+	HTTPClient *http.Client `yaml:"-"`
+}
+
+func (r *Request) GetScenario() string {
+	return r.Scenario
 }
 
 func (r *Request) GetName() string {
@@ -117,6 +143,61 @@ func (r *Request) GetMethod() string {
 	return r.Method
 }
 
+func (r *Request) GetOnFailure() string {
+	return r.OnFailure
+}
+
+// GetWhen returns the parsed condition for sending the request, which is
+// true when there's no condition
+func (r *Request) GetWhen() string {
+	if r.When == "" {
+		return "true"
+	}
+
+	return r.Parser.Parse(r.When)
+}
+
+// GetForeach returns the elements to send the request for, and whether the
+// request has a foreach at all
+func (r *Request) GetForeach() ([]string, bool) {
+	if r.Foreach == nil {
+		return nil, false
+	}
+
+	items := gjson.Parse(r.Parser.Parse(r.Foreach.Items))
+
+	if !items.IsArray() {
+		ForeachNotArrayError.Inc()
+		logrus.
+			WithField("name", r.GetName()).
+			WithField("items", items.Raw).
+			Error("Foreach items is not an array")
+		return nil, true
+	}
+
+	var elements []string
+
+	for _, item := range items.Array() {
+		if r.Foreach.Max > 0 && len(elements) >= r.Foreach.Max {
+			break
+		}
+
+		if item.Type == gjson.String {
+			elements = append(elements, item.String())
+		} else {
+			elements = append(elements, item.Raw)
+		}
+	}
+
+	return elements, true
+}
+
+// GetThinkTime returns the pause after the request, drawn from its think
+// time distribution
+func (r *Request) GetThinkTime() time.Duration {
+	return r.ThinkTime.Sample()
+}
+
 func (r *Request) GetHeader(key string) string {
 	return r.Parser.Parse(r.Headers[key])
 }
@@ -135,78 +216,134 @@ func (r *Request) GetBody() string {
 	return r.Parser.Parse(r.Body)
 }
 
+// Templates returns every templated field of the request
+func (r *Request) Templates() []string {
+	templates := []string{r.URL, r.Body, r.When}
+
+	if r.Foreach != nil {
+		templates = append(templates, r.Foreach.Items)
+	}
+
+	for k, v := range r.Params {
+		templates = append(templates, k, v)
+	}
+
+	for k, v := range r.Headers {
+		templates = append(templates, k, v)
+	}
+
+	return templates
+}
+
+func (r *Request) GetHTTPClient() *http.Client {
+	client := r.HTTPClient
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if r.Session != nil {
+		return r.Session.Client(client)
+	}
+
+	return client
+}
+
 func (r *Request) SetParser(parser HistoryHandler) {
 	r.Parser = parser
 }
 
-func (r *Request) Send() (Response, error) {
+func (r *Request) SetSession(session *Session) {
+	r.Session = session
+}
+
+func (r *Request) Send(ctx context.Context) (Response, error) {
 	var rec Response
-	var req *http.Request
-	var err error
+	var payload []byte
 
 	method := r.GetMethod()
 	url := r.GetUrl()
 
+	rec.Method = method
+	rec.URL = url
+
 	reqLogger := logrus.
 		WithField("method", method).
 		WithField("url", url)
 
 	if r.Body != "" {
-		payload := r.GetBody()
-		reqLogger = reqLogger.WithField("payload", payload)
-
-		req, err = http.NewRequest(
-			method,
-			url,
-			bytes.NewBuffer([]byte(payload)),
-		)
-	} else {
-		req, err = http.NewRequest(
-			method,
-			url,
-			nil,
-		)
+		payload = []byte(r.GetBody())
+		reqLogger = reqLogger.WithField("payload", string(payload))
 	}
 
-	if err != nil {
-		reqLogger.
-			WithError(err).
-			Error("Could not initiate request")
+	headers := r.GetHeaders()
+	attempts := r.Retry.GetAttempts()
 
-		return rec, err
-	}
+	var req *http.Request
+	var res *http.Response
+	var bodybytes []byte
+	var latency float64
+	var timings Timings
+	var message string
+	var err error
 
-	reqLogger.Info("Sending request")
+	for attempt := 1; ; attempt++ {
+		req, err = r.newRequest(ctx, method, url, payload, headers)
 
-	for k, v := range r.GetHeaders() {
-		req.Header.Set(k, v)
-	}
+		if err != nil {
+			reqLogger.
+				WithError(err).
+				Error("Could not initiate request")
 
-	then := time.Now()
-	res, err := http.DefaultClient.Do(req)
+			return rec, err
+		}
 
-	if err != nil {
-		RequestStatusCounter.WithLabelValues(r.GetName(), "error").Inc()
 		reqLogger.
-			WithError(err).
-			Error("Failed doing request")
+			WithField("attempt", attempt).
+			Info("Sending request")
 
-		return rec, err
-	}
+		res, bodybytes, latency, timings, message, err = r.do(req)
 
-	bodybytes, err := ioutil.ReadAll(res.Body)
-	defer res.Body.Close()
+		if ctx.Err() != nil {
+			return rec, r.failed(ctx, reqLogger, err, message)
+		}
+
+		if r.Retry == nil {
+			break
+		}
+
+		status := errorStatus(err)
+
+		if err == nil {
+			status = statusCodeClass(res.StatusCode)
+		}
+
+		RequestAttemptsCounter.WithLabelValues(r.Scenario, r.GetName(), status).Inc()
+
+		if attempt >= attempts || !r.Retry.Retryable(res, err) {
+			r.Retry.Outcome(r.Scenario, r.GetName(), attempt, res, err)
+			break
+		}
+
+		wait := r.Retry.Wait(attempt, res)
 
-	if err != nil {
-		RequestStatusCounter.WithLabelValues(r.GetName(), "error").Inc()
 		reqLogger.
 			WithError(err).
-			Error("Could not read body")
-
-		return rec, err
+			WithField("attempt", attempt).
+			WithField("status", status).
+			WithField("wait", wait.String()).
+			Warn("Retrying request")
+
+		select {
+		case <-ctx.Done():
+			return rec, r.failed(ctx, reqLogger, ctx.Err(), "Retry cancelled")
+		case <-time.After(wait):
+		}
 	}
 
-	latency := time.Since(then).Seconds()
+	if err != nil {
+		return rec, r.failed(ctx, reqLogger, err, message)
+	}
 
 	reqLogger.
 		WithField("statuscode", res.StatusCode).
@@ -221,36 +358,167 @@ func (r *Request) Send() (Response, error) {
 			Warn("Got server error response")
 	}
 
-	r.Expect.Evaluate(r.GetName(), res, body)
+	r.Expect.Scenario = r.Scenario
+	checks, expectation := r.Expect.Evaluate(r.GetName(), res, body)
+
+	if expectation != nil {
+		reqLogger.
+			WithError(expectation).
+			WithField("statuscode", res.StatusCode).
+			Warn("Response did not match expectations")
+
+		ProbeSuccessGauge.WithLabelValues(r.Scenario, r.GetName()).Set(0)
+	} else {
+		ProbeSuccessGauge.WithLabelValues(r.Scenario, r.GetName()).Set(1)
+	}
 
-	rec = Response{Latency: latency, Body: body}
+	rec.Latency = latency
+	rec.Timings = &timings
+	rec.Body = body
+	rec.Expected = r.Expect.Defined()
+	rec.Expectation = expectation
+	rec.Checks = checks
 	rec.SetStatusCode(res.StatusCode)
 
-	RequestStatusCounter.WithLabelValues(r.GetName(), rec.StatusCode).Inc()
-	RequestLatencySummary.WithLabelValues(r.GetName(), rec.StatusCode).Observe(latency)
+	RequestStatusCounter.WithLabelValues(r.Scenario, r.GetName(), rec.StatusCode).Inc()
+	RequestLatencyHistogram.WithLabelValues(r.Scenario, r.GetName(), rec.StatusCode).Observe(latency)
+	timings.Observe(r.Scenario, r.GetName())
+
+	if LatencySummaryEnabled {
+		RequestLatencySummary.WithLabelValues(r.Scenario, r.GetName(), rec.StatusCode).Observe(latency)
+	}
 
 	return rec, nil
 }
 
+func (r *Request) newRequest(
+	ctx context.Context,
+	method string,
+	url string,
+	payload []byte,
+	headers map[string]string,
+) (*http.Request, error) {
+	var body io.Reader
+
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return req, nil
+}
+
+// do sends a single attempt of the request and reads the whole response
+// body, and returns the latency of the attempt and the timings of its
+// phases. On failure, it also returns a message describing what failed.
+func (r *Request) do(req *http.Request) (*http.Response, []byte, float64, Timings, string, error) {
+	if r.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), r.Timeout)
+		defer cancel()
+
+		req = req.WithContext(ctx)
+	}
+
+	var trace tracer
+
+	then := time.Now()
+	res, err := r.GetHTTPClient().Do(trace.Trace(req))
+
+	if err != nil {
+		return nil, nil, 0, Timings{}, "Failed doing request", err
+	}
+
+	bodybytes, err := ioutil.ReadAll(res.Body)
+	defer res.Body.Close()
+
+	if err != nil {
+		return nil, nil, 0, Timings{}, "Could not read body", err
+	}
+
+	return res, bodybytes, time.Since(then).Seconds(), trace.Read(), "", nil
+}
+
+// failed counts and logs a failed request. Requests cancelled by ctx, when
+// shutting down, aren't counted.
+func (r *Request) failed(
+	ctx context.Context,
+	reqLogger *logrus.Entry,
+	err error,
+	message string,
+) error {
+	if ctx.Err() != nil {
+		reqLogger.
+			WithError(err).
+			Info("Request cancelled")
+
+		return ctx.Err()
+	}
+
+	status := errorStatus(err)
+
+	if status == "timeout" {
+		err = fmt.Errorf("Request timed out after %s: %w", r.Timeout, err)
+	}
+
+	RequestStatusCounter.WithLabelValues(r.Scenario, r.GetName(), status).Inc()
+	ProbeSuccessGauge.WithLabelValues(r.Scenario, r.GetName()).Set(0)
+
+	reqLogger.
+		WithError(err).
+		Error(message)
+
+	return err
+}
+
+type Foreach struct {
+	Items string `yaml:"items"`
+	Max   int    `yaml:"max"`
+}
+
 type Response struct {
+	Method         string
+	URL            string
 	Latency        float64
 	StatusCode     string
 	RealStatusCode int
 	Body           string
+	Expected       bool
+	Expectation    error
+	Checks         []*Check
+	Timings        *Timings
 }
 
 func (r *Response) SetStatusCode(statusCode int) {
 	r.RealStatusCode = statusCode
+	r.StatusCode = statusCodeClass(statusCode)
+}
 
+func statusCodeClass(statusCode int) string {
 	if statusCode < 400 {
-		r.StatusCode = "2xx"
-		return
+		return "2xx"
 	}
 
 	if statusCode < 500 {
-		r.StatusCode = "4xx"
-		return
+		return "4xx"
+	}
+
+	return "5xx"
+}
+
+// errorStatus returns the status label of a failed request
+func errorStatus(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
 	}
 
-	r.StatusCode = "5xx"
+	return "error"
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"gopkg.in/jarcoal/httpmock.v1"
 )
@@ -20,8 +27,26 @@ func (f *FakeParser) Parse(input string) string {
 
 func (f *FakeParser) Record(name, body string) {}
 
+func (f *FakeParser) SetItem(index int, item string) {}
+
 var faker HistoryHandler = &FakeParser{}
 
+func counterValue(counter prometheus.Counter) float64 {
+	var metric dto.Metric
+
+	counter.Write(&metric)
+
+	return metric.GetCounter().GetValue()
+}
+
+func gaugeValue(gauge prometheus.Gauge) float64 {
+	var metric dto.Metric
+
+	gauge.Write(&metric)
+
+	return metric.GetGauge().GetValue()
+}
+
 func TestLoadingRequests(t *testing.T) {
 	content := []byte(`
 - name: An request
@@ -124,7 +149,7 @@ func TestGetWith4xx(t *testing.T) {
 		},
 	)
 
-	response, err := request.Send()
+	response, err := request.Send(context.Background())
 
 	if err != nil {
 		t.Fatal("Send returned with an error")
@@ -176,7 +201,7 @@ func TestPostWithHeaders(t *testing.T) {
 		},
 	)
 
-	response, err := request.Send()
+	response, err := request.Send(context.Background())
 
 	if err != nil {
 		t.Fatalf("Send returned with an error: %s", err)
@@ -241,3 +266,124 @@ func TestIterator(t *testing.T) {
 		t.Error("Iterator runs in wrong order")
 	}
 }
+
+func TestSendTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	request := Request{
+		Scenario: "timeouts",
+		Name:     "slow",
+		URL:      server.URL,
+		Method:   "GET",
+		Timeout:  50 * time.Millisecond,
+		Parser:   NewHistory(),
+	}
+
+	counter := RequestStatusCounter.WithLabelValues("timeouts", "slow", "timeout")
+	before := counterValue(counter)
+
+	_, err := request.Send(context.Background())
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send did not time out: %v", err)
+	}
+
+	if counterValue(counter) != before+1 {
+		t.Error("Timeout was not counted")
+	}
+}
+
+func TestSendCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	request := Request{
+		Name:   "cancelled",
+		URL:    server.URL,
+		Method: "GET",
+		Parser: NewHistory(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := request.Send(ctx)
+
+	if err != context.Canceled {
+		t.Errorf("Send was not cancelled: %v", err)
+	}
+}
+
+func TestGetWhen(t *testing.T) {
+	history := NewHistory()
+	history.Record("login", `{"requires2fa":true}`)
+
+	r := Request{Parser: history}
+
+	if r.GetWhen() != "true" {
+		t.Error("Request without condition should be sent")
+	}
+
+	r.When = `{{ fromJson "login" "requires2fa" }}`
+
+	if r.GetWhen() != "true" {
+		t.Errorf("Condition was not parsed: %s", r.GetWhen())
+	}
+}
+
+func TestSendExpectations(t *testing.T) {
+	code := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(code)
+	}))
+	defer server.Close()
+
+	request := Request{
+		Scenario: "probes",
+		Name:     "health",
+		URL:      server.URL,
+		Method:   "GET",
+		Parser:   NewHistory(),
+		Expect:   Expected{StatusCode: "^200$"},
+	}
+
+	probe := ProbeSuccessGauge.WithLabelValues("probes", "health")
+	passed := ExpectationsCounter.WithLabelValues("probes", "health", "status_code", "passed")
+	failed := ExpectationsCounter.WithLabelValues("probes", "health", "status_code", "failed")
+
+	response, err := request.Send(context.Background())
+
+	if err != nil || response.Expectation != nil {
+		t.Fatalf("Request did not match expectations: %v %v", err, response.Expectation)
+	}
+
+	if gaugeValue(probe) != 1 || counterValue(passed) != 1 || counterValue(failed) != 0 {
+		t.Error("Matching expectation was not counted")
+	}
+
+	code = http.StatusServiceUnavailable
+	response, err = request.Send(context.Background())
+
+	if err != nil || response.Expectation == nil || response.Expectation.Error() != "Status code 503, did not match ^200$" {
+		t.Fatalf("Request did not return the failed expectation: %v %v", err, response.Expectation)
+	}
+
+	if gaugeValue(probe) != 0 || counterValue(passed) != 1 || counterValue(failed) != 1 {
+		t.Error("Failed expectation was not counted")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+)
+
+// Result is the outcome of a single request sent by a worker
+type Result struct {
+	Time        time.Time `json:"timestamp"`
+	Scenario    string    `json:"scenario"`
+	Worker      int       `json:"vu"`
+	Iteration   int       `json:"iteration"`
+	Name        string    `json:"name"`
+	Item        *int      `json:"item,omitempty"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	Status      string    `json:"status"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Latency     float64   `json:"latency"`
+	Timings     *Timings  `json:"timings,omitempty"`
+	Bytes       int       `json:"bytes"`
+	Error       string    `json:"error,omitempty"`
+	Expected    bool      `json:"-"`
+	Expectation string    `json:"expectation,omitempty"`
+	Checks      []*Check  `json:"checks,omitempty"`
+}
+
+// Passed tells whether the request got a response matching its expectations
+func (r *Result) Passed() bool {
+	return r.Error == "" && r.Expectation == ""
+}
+
+type ResultHandler interface {
+	Handle(result *Result)
+}
+
+// Results hands every result to all of its handlers
+type Results []ResultHandler
+
+func (r Results) Handle(result *Result) {
+	for _, handler := range r {
+		handler.Handle(result)
+	}
+}
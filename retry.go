@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryDefaults holds the values used for retry policies not setting them
+var RetryDefaults = RetryPolicy{
+	Attempts:      3,
+	StatusCodes:   []int{429, 502, 503, 504},
+	Errors:        []string{"error", "timeout"},
+	Backoff:       100 * time.Millisecond,
+	MaxBackoff:    10 * time.Second,
+	Jitter:        0.2,
+	RetryAfter:    true,
+	MaxRetryAfter: 5 * time.Minute,
+}
+
+type RetryPolicy struct {
+	Attempts      int           `yaml:"attempts"`
+	StatusCodes   []int         `yaml:"status_codes"`
+	Errors        []string      `yaml:"errors"`
+	Backoff       time.Duration `yaml:"backoff"`
+	MaxBackoff    time.Duration `yaml:"max_backoff"`
+	Jitter        float64       `yaml:"jitter"`
+	RetryAfter    bool          `yaml:"retry_after"`
+	MaxRetryAfter time.Duration `yaml:"max_retry_after"`
+}
+
+func (p *RetryPolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RetryPolicy
+
+	*p = RetryDefaults
+
+	return unmarshal((*plain)(p))
+}
+
+func (p *RetryPolicy) Validate() error {
+	if p.Attempts < 1 {
+		return errors.New("Retry attempts must be at least 1")
+	}
+
+	for _, e := range p.Errors {
+		if e != "error" && e != "timeout" {
+			return fmt.Errorf("Retry errors must be error or timeout, not %q", e)
+		}
+	}
+
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return errors.New("Retry backoff can't be negative")
+	}
+
+	if p.MaxRetryAfter < 0 {
+		return errors.New("Retry max_retry_after can't be negative")
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("Retry jitter must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// GetAttempts returns the maximum number of attempts, which is one without
+// a retry policy
+func (p *RetryPolicy) GetAttempts() int {
+	if p == nil || p.Attempts < 1 {
+		return 1
+	}
+
+	return p.Attempts
+}
+
+// Retryable tells whether an attempt ending with res or err should be
+// retried
+func (p *RetryPolicy) Retryable(res *http.Response, err error) bool {
+	if err != nil {
+		status := errorStatus(err)
+
+		for _, e := range p.Errors {
+			if e == status {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, code := range p.StatusCodes {
+		if code == res.StatusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Wait returns the time to wait after the given attempt. It's an
+// exponential backoff with jitter, capped by the max backoff, or the
+// Retry-After header of 429 and 503 responses, capped by the max retry
+// after instead.
+func (p *RetryPolicy) Wait(attempt int, res *http.Response) time.Duration {
+	if p.RetryAfter && res != nil &&
+		(res.StatusCode == http.StatusTooManyRequests ||
+			res.StatusCode == http.StatusServiceUnavailable) {
+		if wait, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			return capWait(wait, p.MaxRetryAfter)
+		}
+	}
+
+	backoff := float64(p.Backoff) * math.Pow(2, float64(attempt-1))
+	backoff += backoff * p.Jitter * (rand.Float64()*2 - 1)
+
+	return capWait(time.Duration(backoff), p.MaxBackoff)
+}
+
+// capWait limits wait to between 0 and max, unless max is 0
+func capWait(wait, max time.Duration) time.Duration {
+	if wait < 0 {
+		return 0
+	}
+
+	if max > 0 && wait > max {
+		return max
+	}
+
+	return wait
+}
+
+// Outcome counts the final outcome of a retried request. Only a response
+// below 400 is a success, recovered if it took more than one attempt. A
+// final failure is exhausted if it could have been retried, and failed if
+// it couldn't.
+func (p *RetryPolicy) Outcome(scenario, name string, attempts int, res *http.Response, err error) {
+	var outcome string
+
+	switch {
+	case err == nil && res != nil && res.StatusCode < 400 && attempts > 1:
+		outcome = "recovered"
+	case err == nil && res != nil && res.StatusCode < 400:
+		outcome = "succeeded"
+	case p.Retryable(res, err):
+		outcome = "exhausted"
+	default:
+		outcome = "failed"
+	}
+
+	RequestRetryOutcomeCounter.WithLabelValues(scenario, name, outcome).Inc()
+}
+
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(value)
+
+	if err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+
+	if err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	var p RetryPolicy
+
+	err := yaml.Unmarshal([]byte("attempts: 5"), &p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Attempts != 5 || len(p.StatusCodes) != 4 || !p.RetryAfter || p.Backoff != RetryDefaults.Backoff {
+		t.Errorf("Retry policy defaults were not set: %+v", p)
+	}
+}
+
+func TestRetryRecovered(t *testing.T) {
+	called := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		called++
+
+		if called < 3 {
+			res.Header().Set("Retry-After", "0")
+			res.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		res.Write([]byte("Hello!"))
+	}))
+	defer server.Close()
+
+	retry := RetryDefaults
+	request := Request{
+		Scenario: "retries",
+		Name:     "recovered",
+		URL:      server.URL,
+		Method:   "GET",
+		Retry:    &retry,
+		Parser:   NewHistory(),
+	}
+
+	outcome := RequestRetryOutcomeCounter.WithLabelValues("retries", "recovered", "recovered")
+	attempts := RequestAttemptsCounter.WithLabelValues("retries", "recovered", "5xx")
+
+	response, err := request.Send(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 3 || response.Body != "Hello!" {
+		t.Errorf("Request was not retried until success: %d calls", called)
+	}
+
+	if counterValue(outcome) != 1 || counterValue(attempts) != 2 {
+		t.Error("Retry attempts and outcome were not counted")
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	called := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		called++
+		res.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	retry := RetryDefaults
+	retry.Backoff = time.Millisecond
+	request := Request{
+		Scenario: "retries",
+		Name:     "exhausted",
+		URL:      server.URL,
+		Method:   "GET",
+		Retry:    &retry,
+		Parser:   NewHistory(),
+	}
+
+	outcome := RequestRetryOutcomeCounter.WithLabelValues("retries", "exhausted", "exhausted")
+
+	response, err := request.Send(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 3 || response.RealStatusCode != http.StatusBadGateway {
+		t.Errorf("Request was not retried 3 times: %d calls", called)
+	}
+
+	if counterValue(outcome) != 1 {
+		t.Error("Exhausted retry outcome was not counted")
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	for name, codes := range map[string][]int{
+		"failed-first": {http.StatusInternalServerError},
+		"failed-later": {http.StatusServiceUnavailable, http.StatusInternalServerError},
+	} {
+		called := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(codes[called])
+			called++
+		}))
+
+		retry := RetryDefaults
+		retry.Backoff = time.Millisecond
+		request := Request{
+			Scenario: "retries",
+			Name:     name,
+			URL:      server.URL,
+			Method:   "GET",
+			Retry:    &retry,
+			Parser:   NewHistory(),
+		}
+
+		failed := RequestRetryOutcomeCounter.WithLabelValues("retries", name, "failed")
+		recovered := RequestRetryOutcomeCounter.WithLabelValues("retries", name, "recovered")
+
+		_, err := request.Send(context.Background())
+		server.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if called != len(codes) {
+			t.Errorf("%s: expected %d calls, got %d", name, len(codes), called)
+		}
+
+		if counterValue(failed) != 1 || counterValue(recovered) != 0 {
+			t.Errorf("%s: non-retryable failure was not counted as failed", name)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	p := RetryPolicy{
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: time.Second,
+		RetryAfter: true,
+	}
+
+	if p.Wait(1, nil) != 100*time.Millisecond || p.Wait(3, nil) != 400*time.Millisecond {
+		t.Error("Backoff was not exponential")
+	}
+
+	if p.Wait(10, nil) != time.Second {
+		t.Error("Backoff was not capped by max backoff")
+	}
+
+	res := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+	}
+
+	if p.Wait(1, res) != time.Second {
+		t.Error("Retry-After was not honored")
+	}
+
+	res.Header.Set("Retry-After", "60")
+
+	if p.Wait(1, res) != time.Minute {
+		t.Error("Retry-After was capped by max backoff")
+	}
+
+	p.MaxRetryAfter = 30 * time.Second
+
+	if p.Wait(1, res) != 30*time.Second {
+		t.Error("Retry-After was not capped by max retry after")
+	}
+
+	p.Jitter = 0.5
+
+	for i := 0; i < 10; i++ {
+		wait := p.Wait(1, nil)
+
+		if wait < 50*time.Millisecond || wait > 150*time.Millisecond {
+			t.Errorf("Jitter out of range: %s", wait)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Run keeps track of the workers of a load test, and the handlers of their
+// results. Stopping it stops workers from starting new iterations, and it's
+// done when every worker has finished.
+type Run struct {
+	Results  Results
+	stopping chan struct{}
+	done     chan struct{}
+	stop     sync.Once
+	wait     sync.Once
+	workers  sync.WaitGroup
+}
+
+func NewRun() *Run {
+	return &Run{
+		stopping: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Go runs fn as a worker of the run. Workers starting other workers must
+// do so before they return.
+func (r *Run) Go(fn func()) {
+	r.workers.Add(1)
+
+	go func() {
+		defer r.workers.Done()
+		fn()
+	}()
+}
+
+func (r *Run) Stop() {
+	r.stop.Do(func() {
+		close(r.stopping)
+	})
+}
+
+// Stopping is closed when the run is stopped
+func (r *Run) Stopping() <-chan struct{} {
+	return r.stopping
+}
+
+// Done is closed when every worker has finished. It must be called after
+// the first worker is started.
+func (r *Run) Done() <-chan struct{} {
+	r.wait.Do(func() {
+		go func() {
+			r.workers.Wait()
+			close(r.done)
+		}()
+	})
+
+	return r.done
+}
+
+// Wait waits for every worker to finish, and returns false if they didn't
+// within timeout
+func (r *Run) Wait(timeout time.Duration) bool {
+	select {
+	case <-r.Done():
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// AwaitRun waits until every worker of the run is done, or until the run is
+// stopped, goload receives a signal or duration has passed, and then stops
+// the run. In-flight iterations get grace to finish before they're
+// cancelled, unless another signal is received.
+func AwaitRun(
+	run *Run,
+	cancel context.CancelFunc,
+	signals <-chan os.Signal,
+	duration time.Duration,
+	grace time.Duration,
+) {
+	var timeout <-chan time.Time
+
+	if duration > 0 {
+		timeout = time.After(duration)
+	}
+
+	select {
+	case <-run.Done():
+		return
+	case <-run.Stopping():
+	case sig := <-signals:
+		logrus.
+			WithField("signal", sig.String()).
+			Warn("Received signal. Stopping run.")
+	case <-timeout:
+		logrus.
+			WithField("duration", duration.String()).
+			Info("Duration has passed. Stopping run.")
+	}
+
+	run.Stop()
+
+	select {
+	case <-run.Done():
+		return
+	case sig := <-signals:
+		logrus.
+			WithField("signal", sig.String()).
+			Warn("Received another signal. Cancelling in-flight iterations.")
+	case <-time.After(grace):
+		logrus.
+			WithField("grace", grace.String()).
+			Warn("Grace period has passed. Cancelling in-flight iterations.")
+	}
+
+	cancel()
+	<-run.Done()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunDone(t *testing.T) {
+	run := NewRun()
+	release := make(chan struct{})
+
+	run.Go(func() {
+		run.Go(func() { <-release })
+	})
+
+	if run.Wait(50 * time.Millisecond) {
+		t.Fatal("Run was done while a worker was running")
+	}
+
+	close(release)
+
+	if !run.Wait(time.Second) {
+		t.Error("Run was not done when every worker had finished")
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	run := NewRun()
+
+	run.Stop()
+	run.Stop()
+
+	select {
+	case <-run.Stopping():
+	default:
+		t.Error("Run was not stopping after being stopped")
+	}
+}
+
+func TestAwaitRunDuration(t *testing.T) {
+	run := NewRun()
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := false
+
+	run.Go(func() {
+		<-run.Stopping()
+		time.Sleep(50 * time.Millisecond)
+		finished = ctx.Err() == nil
+	})
+
+	then := time.Now()
+	AwaitRun(run, cancel, nil, 50*time.Millisecond, time.Second)
+
+	if time.Since(then) < 100*time.Millisecond {
+		t.Errorf("Run ended before the duration and in-flight work: %s", time.Since(then))
+	}
+
+	if !finished {
+		t.Error("In-flight work was cancelled within the grace period")
+	}
+}
+
+func TestAwaitRunGrace(t *testing.T) {
+	run := NewRun()
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+
+	run.Go(func() { <-ctx.Done() })
+
+	signals <- syscall.SIGTERM
+	then := time.Now()
+	AwaitRun(run, cancel, signals, 0, 50*time.Millisecond)
+
+	if ctx.Err() == nil {
+		t.Error("In-flight work was not cancelled after the grace period")
+	}
+
+	if time.Since(then) > time.Second {
+		t.Errorf("Run did not end after the grace period: %s", time.Since(then))
+	}
+}
@@ -1,27 +1,268 @@
 package main
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	OnFailureContinue      = "continue"
+	OnFailureSkipIteration = "skip_iteration"
+	OnFailureAbortWorker   = "abort_worker"
+	OnFailureAbortRun      = "abort_run"
+)
+
+var (
+	ErrStopWorker = errors.New("Stopping worker")
+	ErrStopRun    = errors.New("Stopping run")
+)
+
 type Runner struct {
-	Requests RequestCollectionHandler
-	History  HistoryHandler
-	Status   *Status
+	Requests  RequestCollectionHandler
+	History   HistoryHandler
+	Status    *Status
+	Feed      *Feed
+	Session   *Session
+	Results   ResultHandler
+	Worker    int
+	Iteration int
 }
 
-func (r *Runner) Run() {
+func (r *Runner) Run(ctx context.Context) error {
+	if r.Feed != nil {
+		err := r.Feed.Next()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Session != nil {
+		r.Session.Begin()
+	}
+
 	for request := r.Requests.First(); request != nil; request = r.Requests.Next() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		request.SetParser(r.History)
+		request.SetSession(r.Session)
+
+		if !condition(request.GetWhen()) {
+			r.skipped(request, "condition")
+			continue
+		}
+
+		elements, foreach := request.GetForeach()
 
-		response, err := request.Send()
+		if foreach && len(elements) == 0 {
+			r.skipped(request, "empty")
+			continue
+		}
+
+		var failure error
+
+		if !foreach {
+			failure = r.send(ctx, request, -1)
+		} else {
+			for i, element := range elements {
+				r.History.SetItem(i, element)
+				failure = r.send(ctx, request, i)
 
-		r.Status.Record(
-			request.GetName(),
-			response.Latency,
-			response.RealStatusCode,
-			response.Body,
-			err,
-		)
+				if ctx.Err() != nil || (failure != nil && onFailureStops(request)) {
+					break
+				}
+			}
+
+			r.History.SetItem(-1, "")
+		}
 
-		if err == nil {
-			r.History.Record(request.GetName(), response.Body)
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+
+		if failure != nil {
+			switch request.GetOnFailure() {
+			case OnFailureSkipIteration:
+				r.skip("failure")
+				return nil
+			case OnFailureAbortWorker:
+				r.skip("failure")
+				return fmt.Errorf("Request %q failed: %s: %w", request.GetName(), failure, ErrStopWorker)
+			case OnFailureAbortRun:
+				r.skip("failure")
+				return fmt.Errorf("Request %q failed: %s: %w", request.GetName(), failure, ErrStopRun)
+			}
+		}
+
+		err := think(ctx, request.GetThinkTime())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// think pauses for the think time of a request, or until ctx is cancelled
+func think(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+// send sends the request and records its response by name, which is the
+// name of the request with the index of the element, item, in foreach
+// requests. It returns the error or failed expectations of the request.
+func (r *Runner) send(ctx context.Context, request RequestHandler, item int) error {
+	name := request.GetName()
+
+	if item >= 0 {
+		name = fmt.Sprintf("%s[%d]", name, item)
+	}
+
+	then := time.Now()
+	response, err := request.Send(ctx)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	r.handle(request, item, then, response, err)
+
+	// Responses not matching their expectations are recorded as errors
+	failure := err
+
+	if failure == nil {
+		failure = response.Expectation
+	}
+
+	r.Status.Record(
+		request.GetScenario(),
+		request.GetName(),
+		item,
+		response.Latency,
+		response.RealStatusCode,
+		response.Body,
+		response.Timings,
+		failure,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	r.History.Record(name, response.Body)
+
+	if name != request.GetName() {
+		r.History.Record(request.GetName(), response.Body)
+	}
+
+	return response.Expectation
+}
+
+// handle hands the outcome of a request to the result handlers
+func (r *Runner) handle(
+	request RequestHandler,
+	item int,
+	then time.Time,
+	response Response,
+	err error,
+) {
+	if r.Results == nil {
+		return
+	}
+
+	result := &Result{
+		Time:       then,
+		Scenario:   request.GetScenario(),
+		Worker:     r.Worker,
+		Iteration:  r.Iteration,
+		Name:       request.GetName(),
+		Method:     response.Method,
+		URL:        response.URL,
+		Status:     response.StatusCode,
+		StatusCode: response.RealStatusCode,
+		Latency:    response.Latency,
+		Timings:    response.Timings,
+		Bytes:      len(response.Body),
+		Expected:   response.Expected,
+		Checks:     response.Checks,
 	}
+
+	if item >= 0 {
+		result.Item = &item
+	}
+
+	if err != nil {
+		result.Status = errorStatus(err)
+		result.Error = err.Error()
+	}
+
+	if response.Expectation != nil {
+		result.Expectation = response.Expectation.Error()
+	}
+
+	r.Results.Handle(result)
+}
+
+func onFailureStops(request RequestHandler) bool {
+	switch request.GetOnFailure() {
+	case "", OnFailureContinue:
+		return false
+	}
+
+	return true
+}
+
+// skip counts the rest of the requests in the iteration as skipped
+func (r *Runner) skip(reason string) {
+	for request := r.Requests.Next(); request != nil; request = r.Requests.Next() {
+		r.skipped(request, reason)
+	}
+}
+
+func (r *Runner) skipped(request RequestHandler, reason string) {
+	RequestSkippedCounter.
+		WithLabelValues(request.GetScenario(), request.GetName(), reason).
+		Inc()
+	r.Status.Skip(request.GetScenario(), request.GetName(), reason)
+}
+
+// condition parses the outcome of a when expression. Anything that isn't a
+// boolean, like the empty output of missing data, is false.
+func condition(value string) bool {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+
+	if err != nil {
+		WhenEvaluateError.Inc()
+		logrus.
+			WithError(err).
+			WithField("when", value).
+			Error("Condition did not evaluate to a boolean")
+		return false
+	}
+
+	return b
 }
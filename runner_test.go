@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type RequestCollectionFaker struct {
@@ -32,15 +37,27 @@ func (r *RequestCollectionFaker) Next() RequestHandler {
 var requestCollectionFaker RequestCollectionHandler = &RequestCollectionFaker{}
 
 type RequestFaker struct {
-	Parser HistoryHandler
-	Name   string
-	Body   string
+	Parser      HistoryHandler
+	Name        string
+	Body        string
+	Err         error
+	Expectation error
+	OnFailure   string
+	When        string
+	Sent        int
+	ThinkTime   time.Duration
 }
 
 func (r *RequestFaker) SetParser(parser HistoryHandler) {
 	r.Parser = parser
 }
 
+func (r *RequestFaker) SetSession(session *Session) {}
+
+func (r *RequestFaker) GetScenario() string {
+	return "faker"
+}
+
 func (r *RequestFaker) GetName() string {
 	return r.Name
 }
@@ -57,14 +74,42 @@ func (r *RequestFaker) GetHeader(key string) string {
 	return ""
 }
 
+func (r *RequestFaker) GetOnFailure() string {
+	return r.OnFailure
+}
+
 func (r *RequestFaker) GetBody() string {
 	return r.Body
 }
 
-func (r *RequestFaker) Send() (Response, error) {
+func (r *RequestFaker) GetWhen() string {
+	if r.When == "" {
+		return "true"
+	}
+
+	return r.When
+}
+
+func (r *RequestFaker) GetForeach() ([]string, bool) {
+	return nil, false
+}
+
+func (r *RequestFaker) GetThinkTime() time.Duration {
+	return r.ThinkTime
+}
+
+func (r *RequestFaker) Send(ctx context.Context) (Response, error) {
+	r.Sent++
+
+	if r.Err != nil {
+		return Response{}, r.Err
+	}
+
 	return Response{
-		StatusCode: "2xx",
-		Body:       fmt.Sprintf("response %s %s", r.Name, r.Body),
+		StatusCode:  "2xx",
+		Body:        fmt.Sprintf("response %s %s", r.Name, r.Body),
+		Expected:    r.Expectation != nil,
+		Expectation: r.Expectation,
 	}, nil
 }
 
@@ -82,6 +127,8 @@ func (h *HistoryFaker) Parse(input string) string {
 	return ""
 }
 
+func (h *HistoryFaker) SetItem(index int, item string) {}
+
 var historyFaker HistoryHandler = &HistoryFaker{}
 
 func TestRun(t *testing.T) {
@@ -98,10 +145,178 @@ func TestRun(t *testing.T) {
 		Status:   NewStatus(),
 	}
 
-	runner.Run()
+	runner.Run(context.Background())
 
 	if history.RecordCalls["name 1"] != "response name 1 body 1" ||
 		history.RecordCalls["name 2"] != "response name 2 body 2" {
 		t.Error("Request send and history does not match")
 	}
 }
+
+func TestRunOnFailure(t *testing.T) {
+	for onFailure, expected := range map[string]error{
+		OnFailureContinue:      nil,
+		OnFailureSkipIteration: nil,
+		OnFailureAbortWorker:   ErrStopWorker,
+		OnFailureAbortRun:      ErrStopRun,
+	} {
+		last := &RequestFaker{Name: "last " + onFailure}
+		requests := RequestCollectionFaker{Requests: []*RequestFaker{
+			&RequestFaker{Name: "failing", Err: errors.New("failed"), OnFailure: onFailure},
+			last,
+		}}
+		runner := Runner{
+			Requests: &requests,
+			History:  &HistoryFaker{RecordCalls: make(map[string]string)},
+			Status:   NewStatus(),
+		}
+
+		skipped := RequestSkippedCounter.WithLabelValues("faker", last.Name, "failure")
+		err := runner.Run(context.Background())
+
+		if !errors.Is(err, expected) || (expected == nil && err != nil) {
+			t.Errorf("On failure %s returned %v", onFailure, err)
+		}
+
+		if onFailure == OnFailureContinue {
+			if last.Sent != 1 || counterValue(skipped) != 0 {
+				t.Error("On failure continue did not send the next request")
+			}
+		} else if last.Sent != 0 || counterValue(skipped) != 1 {
+			t.Errorf("On failure %s did not skip the next request", onFailure)
+		}
+	}
+}
+
+func TestRunExpectationFailure(t *testing.T) {
+	last := &RequestFaker{Name: "last"}
+	requests := RequestCollectionFaker{Requests: []*RequestFaker{
+		&RequestFaker{
+			Name:        "mismatching",
+			Expectation: errors.New("Status code 500, did not match 2.."),
+			OnFailure:   OnFailureAbortWorker,
+		},
+		last,
+	}}
+	status := NewStatus()
+	runner := Runner{
+		Requests: &requests,
+		History:  &HistoryFaker{RecordCalls: make(map[string]string)},
+		Status:   status,
+	}
+
+	err := runner.Run(context.Background())
+	status.Flush()
+
+	if !errors.Is(err, ErrStopWorker) || last.Sent != 0 {
+		t.Errorf("Failed expectation did not stop the worker: %v", err)
+	}
+
+	errs := status.Errors["faker"]["mismatching"]
+
+	if len(errs) != 1 || errs[0].Error != "Status code 500, did not match 2.." {
+		t.Errorf("Failed expectation was not recorded in status: %v", errs)
+	}
+
+	if len(status.Slowest["faker"]["mismatching"]) != 0 {
+		t.Error("Failed expectation was recorded as a slow response")
+	}
+}
+
+func TestRunWhen(t *testing.T) {
+	skipped := &RequestFaker{Name: "skipped", When: "false"}
+	sent := &RequestFaker{Name: "sent", When: "true"}
+	invalid := &RequestFaker{Name: "invalid", When: "maybe"}
+	requests := RequestCollectionFaker{Requests: []*RequestFaker{skipped, sent, invalid}}
+	status := NewStatus()
+	runner := Runner{
+		Requests: &requests,
+		History:  &HistoryFaker{RecordCalls: make(map[string]string)},
+		Status:   status,
+	}
+
+	counter := RequestSkippedCounter.WithLabelValues("faker", "skipped", "condition")
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if skipped.Sent != 0 || sent.Sent != 1 || invalid.Sent != 0 {
+		t.Error("Requests were not sent according to their conditions")
+	}
+
+	if counterValue(counter) != 1 || status.Skipped["faker"]["skipped"]["condition"] != 1 {
+		t.Error("Skipped request was not counted")
+	}
+}
+
+func TestRunForeach(t *testing.T) {
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+
+		if req.URL.Path == "/list" {
+			res.Write([]byte(`{"items":[{"id":"a"},{"id":"b"}],"empty":[]}`))
+			return
+		}
+
+		res.Write([]byte(`{"path":"` + req.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	requests := RequestCollection{Requests: []*Request{
+		{Scenario: "foreach", Name: "list", Method: "GET", URL: server.URL + "/list"},
+		{
+			Scenario: "foreach",
+			Name:     "item",
+			Method:   "GET",
+			URL:      server.URL + "/items/{{ itemIndex }}/{{ item \"id\" }}",
+			Foreach:  &Foreach{Items: `{{ fromJson "list" "items" }}`},
+		},
+		{
+			Scenario: "foreach",
+			Name:     "none",
+			Method:   "GET",
+			URL:      server.URL + "/none",
+			Foreach:  &Foreach{Items: `{{ fromJson "list" "empty" }}`},
+		},
+	}}
+	history := NewHistory()
+	status := NewStatus()
+	summary := NewSummary()
+	runner := Runner{Requests: &requests, History: history, Status: status, Results: summary}
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(paths) != "[/list /items/0/a /items/1/b]" {
+		t.Errorf("Foreach requests were not sent for every item: %v", paths)
+	}
+
+	if history.From("item[0]") == nil || history.From("item").Json("path") != "/items/1/b" {
+		t.Error("Foreach responses were not recorded by index and name")
+	}
+
+	status.Flush()
+	slowest, _ := status.Samples("foreach", "item")
+
+	if len(slowest) == 0 || slowest[0].Item == nil {
+		t.Error("Foreach responses were not recorded in status by name with their index")
+	}
+
+	if status.Skipped["foreach"]["none"]["empty"] != 1 {
+		t.Error("Foreach over an empty array was not skipped")
+	}
+
+	if history.Item != nil {
+		t.Error("Foreach item was not cleared")
+	}
+
+	report := summary.Report()
+
+	if len(report.Requests) != 2 || report.Requests[0].Name != "item" || report.Requests[0].Count != 2 {
+		t.Errorf("Results were not handled by request name: %+v", report.Requests)
+	}
+}
@@ -0,0 +1,843 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaErrors is the number of validation errors of a response reported
+const SchemaErrors = 5
+
+var schemaTypes = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// schemaUnsupported are keywords rejected by Load, rather than ignored,
+// since bodies not matching them would pass
+var schemaUnsupported = []string{"unevaluatedProperties", "unevaluatedItems", "$dynamicRef", "$recursiveRef"}
+
+// JSONSchema validates json bodies against a JSON Schema, set inline or as a
+// path to a json file relative to the targets file. It supports the
+// validation keywords of draft 7 and later, except for formats, which are
+// ignored, and remote references, dynamic references and unevaluated
+// properties and items, which are rejected by Load.
+type JSONSchema struct {
+	File     string
+	schema   interface{}
+	patterns map[string]*regexp.Regexp
+	compiled map[string]bool
+}
+
+func (s *JSONSchema) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var file string
+
+	if unmarshal(&file) == nil {
+		s.File = file
+		return nil
+	}
+
+	var inline interface{}
+
+	err := unmarshal(&inline)
+
+	if err != nil {
+		return err
+	}
+
+	s.schema = jsonValue(inline)
+
+	return nil
+}
+
+// Source returns the file of the schema, or inline
+func (s *JSONSchema) Source() string {
+	if s.File != "" {
+		return s.File
+	}
+
+	return "inline"
+}
+
+// Load reads the schema file, if any, and checks the schema
+func (s *JSONSchema) Load(dir string) error {
+	if s.File != "" {
+		data, err := ioutil.ReadFile(resolvePath(dir, s.File))
+
+		if err != nil {
+			return err
+		}
+
+		var schema interface{}
+
+		err = json.Unmarshal(data, &schema)
+
+		if err != nil {
+			return fmt.Errorf("Schema %s is not valid json: %s", s.File, err)
+		}
+
+		s.schema = schema
+	}
+
+	s.patterns = make(map[string]*regexp.Regexp)
+	s.compiled = make(map[string]bool)
+
+	return s.compile(s.schema, "#")
+}
+
+// Check validates body against the schema
+func (s *JSONSchema) Check(body string) *Check {
+	check := &Check{Part: "schema", Expected: s.Source()}
+
+	if s.patterns == nil {
+		check.Message = "Schema was not loaded"
+		return check
+	}
+
+	var value interface{}
+
+	err := json.Unmarshal([]byte(body), &value)
+
+	if err != nil {
+		check.Message = fmt.Sprintf("Schema: body is not valid json: %s", err)
+		return check
+	}
+
+	errs := s.Validate(value)
+
+	if len(errs) == 0 {
+		check.Passed = true
+		return check
+	}
+
+	messages := make([]string, 0, SchemaErrors)
+
+	for i, err := range errs {
+		if i == SchemaErrors {
+			messages = append(messages, fmt.Sprintf("%d more", len(errs)-i))
+			break
+		}
+
+		messages = append(messages, err.Error())
+	}
+
+	check.Message = fmt.Sprintf("Schema %s did not match: %s", s.Source(), strings.Join(messages, "; "))
+
+	return check
+}
+
+// Validate returns every place value doesn't match the schema
+func (s *JSONSchema) Validate(value interface{}) []*SchemaError {
+	return s.validate(s.schema, value, "", make(map[schemaRef]bool))
+}
+
+// schemaRef is a referenced schema being validated at a path
+type schemaRef struct {
+	schema uintptr
+	path   string
+}
+
+// newSchemaRef returns the key of schema at path, if it's an object
+func newSchemaRef(schema interface{}, path string) (schemaRef, bool) {
+	if _, ok := schema.(map[string]interface{}); !ok {
+		return schemaRef{}, false
+	}
+
+	return schemaRef{reflect.ValueOf(schema).Pointer(), path}, true
+}
+
+// SchemaError is why the value at a gjson-like path didn't match the schema
+type SchemaError struct {
+	Path   string
+	Reason string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+
+	return e.Path + ": " + e.Reason
+}
+
+func (s *JSONSchema) validate(schema, value interface{}, path string, refs map[schemaRef]bool) []*SchemaError {
+	var errs []*SchemaError
+
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			fail("no value is allowed")
+		}
+
+		return errs
+	}
+
+	keywords, ok := schema.(map[string]interface{})
+
+	if !ok {
+		return errs
+	}
+
+	if ref, ok := keywords["$ref"].(string); ok {
+		resolved, _ := s.resolve(ref)
+
+		// A reference back to a schema already being validated at the same
+		// path would recurse forever, and can't add any errors
+		if key, ok := newSchemaRef(resolved, path); !ok {
+			errs = append(errs, s.validate(resolved, value, path, refs)...)
+		} else if !refs[key] {
+			refs[key] = true
+			errs = append(errs, s.validate(resolved, value, path, refs)...)
+			delete(refs, key)
+		}
+	}
+
+	if t, ok := keywords["type"]; ok && !schemaTypeMatches(t, value) {
+		fail("expected %s, got %s", schemaTypeNames(t), schemaType(value))
+		return errs
+	}
+
+	if enum, ok := keywords["enum"].([]interface{}); ok && !schemaContains(enum, value) {
+		fail("%s is not one of %s", formatJSON(value), formatJSON(enum))
+	}
+
+	if c, ok := keywords["const"]; ok && !reflect.DeepEqual(c, value) {
+		fail("%s is not %s", formatJSON(value), formatJSON(c))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(keywords, value, path, refs)...)
+	case []interface{}:
+		errs = append(errs, s.validateArray(keywords, value, path, refs)...)
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+
+		if min, ok := keywords["minLength"].(float64); ok && length < min {
+			fail("length %v is less than %v", length, min)
+		}
+
+		if max, ok := keywords["maxLength"].(float64); ok && length > max {
+			fail("length %v is more than %v", length, max)
+		}
+
+		if pattern, ok := keywords["pattern"].(string); ok && !s.match(pattern, value) {
+			fail("%q does not match %s", value, pattern)
+		}
+	case float64:
+		if min, ok := keywords["minimum"].(float64); ok && value < min {
+			fail("%v is less than %v", value, min)
+		}
+
+		if max, ok := keywords["maximum"].(float64); ok && value > max {
+			fail("%v is more than %v", value, max)
+		}
+
+		if min, ok := keywords["exclusiveMinimum"].(float64); ok && value <= min {
+			fail("%v is not more than %v", value, min)
+		}
+
+		if max, ok := keywords["exclusiveMaximum"].(float64); ok && value >= max {
+			fail("%v is not less than %v", value, max)
+		}
+
+		if multiple, ok := keywords["multipleOf"].(float64); ok && multiple > 0 {
+			if quotient := value / multiple; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+				fail("%v is not a multiple of %v", value, multiple)
+			}
+		}
+	}
+
+	if all, ok := keywords["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(sub, value, path, refs)...)
+		}
+	}
+
+	if anyOf, ok := keywords["anyOf"].([]interface{}); ok && s.matching(anyOf, value, path, refs) == 0 {
+		fail("does not match any of anyOf")
+	}
+
+	if oneOf, ok := keywords["oneOf"].([]interface{}); ok {
+		if matching := s.matching(oneOf, value, path, refs); matching != 1 {
+			fail("matches %d of oneOf, not 1", matching)
+		}
+	}
+
+	if not, ok := keywords["not"]; ok && len(s.validate(not, value, path, refs)) == 0 {
+		fail("matches not")
+	}
+
+	if condition, ok := keywords["if"]; ok {
+		if len(s.validate(condition, value, path, refs)) == 0 {
+			if then, ok := keywords["then"]; ok {
+				errs = append(errs, s.validate(then, value, path, refs)...)
+			}
+		} else if otherwise, ok := keywords["else"]; ok {
+			errs = append(errs, s.validate(otherwise, value, path, refs)...)
+		}
+	}
+
+	return errs
+}
+
+func (s *JSONSchema) validateObject(keywords, value map[string]interface{}, path string, refs map[schemaRef]bool) []*SchemaError {
+	var errs []*SchemaError
+
+	if required, ok := keywords["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := value[name]; !ok {
+					errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("%s is required", name)})
+				}
+			}
+		}
+	}
+
+	count := float64(len(value))
+
+	if min, ok := keywords["minProperties"].(float64); ok && count < min {
+		errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v properties, less than %v", count, min)})
+	}
+
+	if max, ok := keywords["maxProperties"].(float64); ok && count > max {
+		errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v properties, more than %v", count, max)})
+	}
+
+	if names, ok := keywords["propertyNames"]; ok {
+		for _, name := range sortedJSONKeys(value) {
+			for _, err := range s.validate(names, name, path, refs) {
+				errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("property name %q: %s", name, err.Reason)})
+			}
+		}
+	}
+
+	// Draft 7 dependencies are either a list of required properties, like
+	// dependentRequired, or a schema, like dependentSchemas
+	for _, keyword := range []string{"dependencies", "dependentRequired", "dependentSchemas"} {
+		dependencies, _ := keywords[keyword].(map[string]interface{})
+
+		for _, name := range sortedJSONKeys(dependencies) {
+			if _, ok := value[name]; !ok {
+				continue
+			}
+
+			required, ok := dependencies[name].([]interface{})
+
+			if !ok {
+				errs = append(errs, s.validate(dependencies[name], value, path, refs)...)
+				continue
+			}
+
+			for _, dependency := range required {
+				if dependency, ok := dependency.(string); ok {
+					if _, ok := value[dependency]; !ok {
+						errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("%s is required by %s", dependency, name)})
+					}
+				}
+			}
+		}
+	}
+
+	properties, _ := keywords["properties"].(map[string]interface{})
+	patterns, _ := keywords["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := keywords["additionalProperties"]
+
+	for _, name := range sortedJSONKeys(value) {
+		property := joinSchemaPath(path, name)
+		matched := false
+
+		if sub, ok := properties[name]; ok {
+			matched = true
+			errs = append(errs, s.validate(sub, value[name], property, refs)...)
+		}
+
+		for pattern, sub := range patterns {
+			if s.match(pattern, name) {
+				matched = true
+				errs = append(errs, s.validate(sub, value[name], property, refs)...)
+			}
+		}
+
+		if matched || !hasAdditional {
+			continue
+		}
+
+		if allowed, ok := additional.(bool); ok && !allowed {
+			errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("%s is not allowed", name)})
+		} else {
+			errs = append(errs, s.validate(additional, value[name], property, refs)...)
+		}
+	}
+
+	return errs
+}
+
+func (s *JSONSchema) validateArray(keywords map[string]interface{}, value []interface{}, path string, refs map[schemaRef]bool) []*SchemaError {
+	var errs []*SchemaError
+
+	count := float64(len(value))
+
+	if min, ok := keywords["minItems"].(float64); ok && count < min {
+		errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v items, less than %v", count, min)})
+	}
+
+	if max, ok := keywords["maxItems"].(float64); ok && count > max {
+		errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v items, more than %v", count, max)})
+	}
+
+	if unique, ok := keywords["uniqueItems"].(bool); ok && unique {
+		for i := range value {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("items %d and %d are equal", j, i)})
+				}
+			}
+		}
+	}
+
+	// Draft 2020-12 prefixItems, or draft 7 items as an array, check items
+	// by position, and the rest are checked by items or additionalItems
+	prefix, ok := keywords["prefixItems"].([]interface{})
+	rest, hasRest := keywords["items"]
+
+	if tuple, isTuple := rest.([]interface{}); isTuple {
+		prefix, ok = tuple, true
+		rest, hasRest = keywords["additionalItems"]
+	}
+
+	for i, item := range value {
+		itemPath := joinSchemaPath(path, strconv.Itoa(i))
+
+		if ok && i < len(prefix) {
+			errs = append(errs, s.validate(prefix[i], item, itemPath, refs)...)
+		} else if hasRest {
+			errs = append(errs, s.validate(rest, item, itemPath, refs)...)
+		}
+	}
+
+	if contains, ok := keywords["contains"]; ok {
+		found := 0.0
+
+		for _, item := range value {
+			if len(s.validate(contains, item, path, refs)) == 0 {
+				found++
+			}
+		}
+
+		min, hasMin := keywords["minContains"].(float64)
+
+		if !hasMin {
+			min = 1
+		}
+
+		if found == 0 && min > 0 {
+			errs = append(errs, &SchemaError{Path: path, Reason: "has no item matching contains"})
+		} else if found < min {
+			errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v items matching contains, less than %v", found, min)})
+		}
+
+		if max, ok := keywords["maxContains"].(float64); ok && found > max {
+			errs = append(errs, &SchemaError{Path: path, Reason: fmt.Sprintf("has %v items matching contains, more than %v", found, max)})
+		}
+	}
+
+	return errs
+}
+
+// matching returns the number of schemas value matches
+func (s *JSONSchema) matching(schemas []interface{}, value interface{}, path string, refs map[schemaRef]bool) int {
+	matching := 0
+
+	for _, sub := range schemas {
+		if len(s.validate(sub, value, path, refs)) == 0 {
+			matching++
+		}
+	}
+
+	return matching
+}
+
+// resolve returns the part of the schema a local reference, like
+// #/definitions/user or #/$defs/user, points to
+func (s *JSONSchema) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("Schema reference %q must be local, like #/$defs/name", ref)
+	}
+
+	resolved := s.schema
+
+	for _, token := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if token == "" {
+			continue
+		}
+
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		keywords, ok := resolved.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("Schema reference %q does not exist", ref)
+		}
+
+		if resolved, ok = keywords[token]; !ok {
+			return nil, fmt.Errorf("Schema reference %q does not exist", ref)
+		}
+	}
+
+	return resolved, nil
+}
+
+// schemaAnnotations are keywords that don't validate anything
+var schemaAnnotations = []string{"$ref", "$defs", "definitions", "$schema", "$id", "$comment", "title", "description"}
+
+// follow checks that ref, and any references it leads to, exist. A chain of
+// references looping back on itself without any other keywords is an error,
+// since it would never validate anything.
+func (s *JSONSchema) follow(ref string) error {
+	followed := make(map[string]bool)
+
+	for !followed[ref] {
+		followed[ref] = true
+		resolved, err := s.resolve(ref)
+
+		if err != nil {
+			return err
+		}
+
+		keywords, ok := resolved.(map[string]interface{})
+
+		if !ok {
+			return nil
+		}
+
+		for keyword := range keywords {
+			if !containsString(schemaAnnotations, keyword) {
+				return nil
+			}
+		}
+
+		if ref, ok = keywords["$ref"].(string); !ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Schema reference %q loops without any keywords", ref)
+}
+
+// compile checks the keywords of the schema, and compiles its patterns
+func (s *JSONSchema) compile(schema interface{}, at string) error {
+	switch schema := schema.(type) {
+	case bool:
+		return nil
+	case []interface{}:
+		for i, sub := range schema {
+			err := s.compile(sub, fmt.Sprintf("%s/%d", at, i))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case map[string]interface{}:
+		for _, keyword := range sortedJSONKeys(schema) {
+			err := s.keyword(keyword, schema[keyword], at+"/"+keyword)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("Schema at %s must be an object or a boolean", at)
+}
+
+func (s *JSONSchema) keyword(keyword string, value interface{}, at string) error {
+	switch keyword {
+	case "type":
+		names, ok := value.([]interface{})
+
+		if !ok {
+			names = []interface{}{value}
+		}
+
+		for _, name := range names {
+			if n, ok := name.(string); !ok || !containsString(schemaTypes, n) {
+				return fmt.Errorf("Schema type at %s must be %s, not %v", at, strings.Join(schemaTypes, ", "), name)
+			}
+		}
+	case "pattern":
+		return s.pattern(value, at)
+	case "patternProperties":
+		properties, ok := value.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("Schema patternProperties at %s must be an object", at)
+		}
+
+		for _, pattern := range sortedJSONKeys(properties) {
+			err := s.pattern(pattern, at)
+
+			if err == nil {
+				err = s.compile(properties[pattern], at+"/"+pattern)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	case "$ref":
+		ref, ok := value.(string)
+
+		if !ok {
+			return fmt.Errorf("Schema $ref at %s must be a string", at)
+		}
+
+		err := s.follow(ref)
+
+		if err != nil || s.compiled[ref] {
+			return err
+		}
+
+		// References may point anywhere in the schema, not only to places
+		// compiled as schemas, so their targets are compiled too
+		s.compiled[ref] = true
+		resolved, _ := s.resolve(ref)
+
+		return s.compile(resolved, ref)
+	case "properties", "definitions", "$defs", "dependentSchemas":
+		properties, ok := value.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("Schema %s at %s must be an object", keyword, at)
+		}
+
+		for _, name := range sortedJSONKeys(properties) {
+			err := s.compile(properties[name], at+"/"+name)
+
+			if err != nil {
+				return err
+			}
+		}
+	case "items", "prefixItems", "allOf", "anyOf", "oneOf", "not", "contains",
+		"additionalItems", "additionalProperties", "propertyNames", "if", "then", "else":
+		return s.compile(value, at)
+	case "dependencies", "dependentRequired":
+		dependencies, ok := value.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("Schema %s at %s must be an object", keyword, at)
+		}
+
+		for _, name := range sortedJSONKeys(dependencies) {
+			_, isList := dependencies[name].([]interface{})
+
+			if keyword == "dependentRequired" && !isList {
+				return fmt.Errorf("Schema dependentRequired at %s/%s must be a list of names", at, name)
+			}
+
+			var err error
+
+			if isList {
+				err = s.keyword("required", dependencies[name], at+"/"+name)
+			} else {
+				err = s.compile(dependencies[name], at+"/"+name)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	case "required":
+		names, ok := value.([]interface{})
+
+		for _, name := range names {
+			if _, isString := name.(string); !isString {
+				ok = false
+			}
+		}
+
+		if !ok {
+			return fmt.Errorf("Schema required at %s must be a list of names", at)
+		}
+	case "enum":
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("Schema enum at %s must be a list", at)
+		}
+	case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties",
+		"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+		"minContains", "maxContains":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("Schema %s at %s must be a number", keyword, at)
+		}
+	default:
+		if containsString(schemaUnsupported, keyword) {
+			return fmt.Errorf("Schema keyword %s at %s is not supported", keyword, at)
+		}
+	}
+
+	return nil
+}
+
+// match tells whether value matches a pattern compiled by Load. A pattern
+// that wasn't compiled never matches.
+func (s *JSONSchema) match(pattern, value string) bool {
+	re, ok := s.patterns[pattern]
+
+	return ok && re.MatchString(value)
+}
+
+func (s *JSONSchema) pattern(value interface{}, at string) error {
+	pattern, ok := value.(string)
+
+	if !ok {
+		return fmt.Errorf("Schema pattern at %s must be a string", at)
+	}
+
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return fmt.Errorf("Schema pattern at %s: %s", at, err)
+	}
+
+	s.patterns[pattern] = re
+
+	return nil
+}
+
+func schemaType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+	}
+
+	return "number"
+}
+
+func schemaTypeMatches(t, value interface{}) bool {
+	names, ok := t.([]interface{})
+
+	if !ok {
+		names = []interface{}{t}
+	}
+
+	actual := schemaType(value)
+
+	for _, name := range names {
+		if name == actual || name == "number" && actual == "integer" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func schemaTypeNames(t interface{}) string {
+	names, ok := t.([]interface{})
+
+	if !ok {
+		return fmt.Sprint(t)
+	}
+
+	parts := make([]string, len(names))
+
+	for i, name := range names {
+		parts[i] = fmt.Sprint(name)
+	}
+
+	return strings.Join(parts, " or ")
+}
+
+func schemaContains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinSchemaPath returns the path of a property or item, like user.id
+func joinSchemaPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func sortedJSONKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
+
+// jsonValue converts yaml values to the types json values are unmarshalled
+// to, with string keys and float64 numbers
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+
+		for k, v := range value {
+			converted[fmt.Sprint(k)] = jsonValue(v)
+		}
+
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+
+		for i, v := range value {
+			converted[i] = jsonValue(v)
+		}
+
+		return converted
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	}
+
+	return value
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const userSchema = `
+type: object
+required: [id, name, roles]
+additionalProperties: false
+properties:
+  id:
+    type: integer
+    minimum: 1
+  name:
+    type: string
+    minLength: 1
+    pattern: ^[A-Z]
+  email:
+    type: [string, "null"]
+  roles:
+    type: array
+    minItems: 1
+    uniqueItems: true
+    items:
+      $ref: '#/$defs/role'
+$defs:
+  role:
+    enum: [admin, user]
+`
+
+func loadSchema(t *testing.T, content string) *JSONSchema {
+	var schema JSONSchema
+
+	if err := yaml.Unmarshal([]byte(content), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := schema.Load("."); err != nil {
+		t.Fatal(err)
+	}
+
+	return &schema
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema := loadSchema(t, userSchema)
+
+	tests := []struct {
+		body   string
+		errors []string
+	}{
+		{`{"id":1,"name":"Alice","email":null,"roles":["admin"]}`, nil},
+		{`{"id":1.5,"name":"alice","roles":["admin","admin"]}`, []string{
+			"id: expected integer, got number",
+			`name: "alice" does not match ^[A-Z]`,
+			"roles: items 0 and 1 are equal",
+		}},
+		{`{"id":0,"name":"","roles":["root"],"admin":true}`, []string{
+			"admin is not allowed",
+			"id: 0 is less than 1",
+			"name: length 0 is less than 1",
+			`name: "" does not match ^[A-Z]`,
+			`roles.0: "root" is not one of ["admin","user"]`,
+		}},
+		{`{"name":"Alice","email":1,"roles":[]}`, []string{
+			"id is required",
+			"email: expected string or null, got integer",
+			"roles: has 0 items, less than 1",
+		}},
+		{`[]`, []string{"expected object, got array"}},
+	}
+
+	for _, test := range tests {
+		check := schema.Check(test.body)
+
+		if check.Passed != (len(test.errors) == 0) {
+			t.Errorf("Body %s was not checked: %s", test.body, check.Message)
+			continue
+		}
+
+		var value interface{}
+
+		yaml.Unmarshal([]byte(test.body), &value)
+
+		var errs []string
+
+		for _, err := range schema.Validate(jsonValue(value)) {
+			errs = append(errs, err.Error())
+		}
+
+		if strings.Join(errs, "\n") != strings.Join(test.errors, "\n") {
+			t.Errorf("Body %s did not match\n%s\n%s", test.body, strings.Join(errs, "\n"), strings.Join(test.errors, "\n"))
+		}
+	}
+}
+
+func TestJSONSchemaCombinators(t *testing.T) {
+	schema := loadSchema(t, `
+oneOf:
+  - {type: integer, multipleOf: 2}
+  - {type: integer, exclusiveMaximum: 10}
+not:
+  const: 4
+`)
+
+	for body, message := range map[string]string{
+		"12": "",
+		"3":  "",
+		"6":  "matches 2 of oneOf, not 1",
+		"4":  "matches 2 of oneOf, not 1; matches not",
+		"11": "matches 0 of oneOf, not 1",
+	} {
+		check := schema.Check(body)
+		expected := ""
+
+		if message != "" {
+			expected = "Schema inline did not match: " + message
+		}
+
+		if check.Message != expected {
+			t.Errorf("Body %s did not match\n%s\n%s", body, check.Message, expected)
+		}
+	}
+}
+
+func TestJSONSchemaDependencies(t *testing.T) {
+	schema := loadSchema(t, `
+propertyNames: {pattern: '^[a-z]+$'}
+dependentRequired:
+  card: [cvc]
+dependentSchemas:
+  discount: {required: [code]}
+dependencies:
+  street: [city]
+  gift: {properties: {gift: {type: boolean}}}
+`)
+
+	for body, message := range map[string]string{
+		`{"card":1,"cvc":2}`:       "",
+		`{"Card":1}`:               `property name "Card": "Card" does not match ^[a-z]+$`,
+		`{"card":1}`:               "cvc is required by card",
+		`{"discount":1}`:           "code is required",
+		`{"street":"x"}`:           "city is required by street",
+		`{"gift":"yes"}`:           "gift: expected boolean, got string",
+		`{"city":"x","gift":true}`: "",
+	} {
+		check := schema.Check(body)
+		expected := ""
+
+		if message != "" {
+			expected = "Schema inline did not match: " + message
+		}
+
+		if check.Message != expected {
+			t.Errorf("Body %s did not match\n%s\n%s", body, check.Message, expected)
+		}
+	}
+}
+
+func TestJSONSchemaConditionalsAndContains(t *testing.T) {
+	schema := loadSchema(t, `
+if: {type: array}
+then:
+  contains: {const: 1}
+  minContains: 2
+  maxContains: 3
+else:
+  type: string
+`)
+
+	for body, message := range map[string]string{
+		`[1,1,2]`:   "",
+		`"text"`:    "",
+		`[2]`:       "has no item matching contains",
+		`[1,2]`:     "has 1 items matching contains, less than 2",
+		`[1,1,1,1]`: "has 4 items matching contains, more than 3",
+		`{"a":1}`:   "expected string, got object",
+	} {
+		check := schema.Check(body)
+		expected := ""
+
+		if message != "" {
+			expected = "Schema inline did not match: " + message
+		}
+
+		if check.Message != expected {
+			t.Errorf("Body %s did not match\n%s\n%s", body, check.Message, expected)
+		}
+	}
+}
+
+func TestJSONSchemaFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "user.json"), []byte(`{"type":"object","required":["id"]}`), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := JSONSchema{File: "user.json"}
+
+	if err := schema.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if check := schema.Check(`{"name":"Alice"}`); check.Message != "Schema user.json did not match: id is required" {
+		t.Errorf("Schema file was not used: %s", check.Message)
+	}
+
+	if check := schema.Check(`not json`); check.Passed {
+		t.Error("Body that is not json passed")
+	}
+}
+
+func TestJSONSchemaLoadErrors(t *testing.T) {
+	for _, content := range []string{
+		"type: integr",
+		"properties: {id: {pattern: '[a-'}}",
+		"items: {$ref: '#/$defs/missing'}",
+		"$ref: http://some-host/schema.json",
+		"minLength: short",
+		"required: id",
+		"$ref: '#'",
+		"{$ref: '#/$defs/a', $defs: {a: {$ref: '#/$defs/a'}}}",
+		"{$ref: '#/$defs/a', $defs: {a: {$ref: '#/$defs/b'}, b: {$ref: '#'}}}",
+		"unevaluatedProperties: false",
+		"items: {unevaluatedItems: false}",
+		"dependentRequired: {a: b}",
+		"maxContains: many",
+	} {
+		var schema JSONSchema
+
+		if err := yaml.Unmarshal([]byte(content), &schema); err != nil {
+			t.Fatal(err)
+		}
+
+		if schema.Load(".") == nil {
+			t.Errorf("Invalid schema was loaded: %s", content)
+		}
+	}
+}
+
+func TestJSONSchemaRefOutsideDefs(t *testing.T) {
+	schema := loadSchema(t, `
+components:
+  user:
+    type: object
+    required: [name]
+    properties:
+      name: {type: string, pattern: ^a}
+$ref: '#/components/user'
+`)
+
+	for body, passed := range map[string]bool{
+		`{"name":"alice"}`: true,
+		`{"name":"bob"}`:   false,
+		`{}`:               false,
+	} {
+		if check := schema.Check(body); check.Passed != passed {
+			t.Errorf("Body %s passed %t, not %t: %s", body, check.Passed, passed, check.Message)
+		}
+	}
+
+	var invalid JSONSchema
+
+	if err := yaml.Unmarshal([]byte("{components: {user: {required: name}}, $ref: '#/components/user'}"), &invalid); err != nil {
+		t.Fatal(err)
+	}
+
+	if invalid.Load(".") == nil {
+		t.Error("Invalid schema behind a reference was loaded")
+	}
+}
+
+func TestJSONSchemaRecursion(t *testing.T) {
+	schema := loadSchema(t, `
+$ref: '#'
+type: object
+properties:
+  children:
+    type: array
+    items: {$ref: '#'}
+`)
+
+	for body, passed := range map[string]bool{
+		`{}`:                              true,
+		`{"children":[{"children":[]}]}`:  true,
+		`1`:                               false,
+		`{"children":[{"children":[1]}]}`: false,
+	} {
+		if check := schema.Check(body); check.Passed != passed {
+			t.Errorf("Body %s passed %t, not %t: %s", body, check.Passed, passed, check.Message)
+		}
+	}
+}
+
+func TestEvaluateSchema(t *testing.T) {
+	e := Expected{Scenario: "schemas", Schema: loadSchema(t, "{type: object}")}
+	passed := ExpectedResponseCounter.WithLabelValues("schemas", "user", "schema")
+	failed := ExpectationsCounter.WithLabelValues("schemas", "user", "schema", "failed")
+	expectedFailed := ExpectedResponseCounter.WithLabelValues("schemas", "user", "schema_failed")
+
+	r := http.Response{StatusCode: 200, Header: http.Header{}}
+
+	if _, err := e.Evaluate("user", &r, `{}`); err != nil {
+		t.Errorf("Matching body failed: %s", err)
+	}
+
+	_, err := e.Evaluate("user", &r, `[]`)
+
+	if err == nil || err.Error() != "Schema inline did not match: expected object, got array" {
+		t.Errorf("Failed schema was not returned: %v", err)
+	}
+
+	if counterValue(passed) != 1 || counterValue(failed) != 1 {
+		t.Error("Schema checks were not counted")
+	}
+
+	if counterValue(expectedFailed) != 1 {
+		t.Errorf("Expected 1 failed schema check, got %f", counterValue(expectedFailed))
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+const (
+	SessionIteration = "iteration"
+	SessionWorker    = "worker"
+	SessionNone      = "none"
+)
+
+// Session holds the cookies of a single worker
+type Session struct {
+	Mode string
+	Jar  http.CookieJar
+	jar  *sessionJar
+}
+
+func NewSession(mode string) *Session {
+	s := &Session{Mode: mode}
+	s.Reset()
+
+	return s
+}
+
+// Begin is called at the start of every iteration and resets the session,
+// unless it's kept for the lifetime of the worker
+func (s *Session) Begin() {
+	if s.Mode != SessionWorker {
+		s.Reset()
+	}
+}
+
+func (s *Session) Reset() {
+	s.Jar = nil
+	s.jar = nil
+
+	if s.Mode != SessionNone {
+		// cookiejar.New never returns an error without options
+		jar, _ := cookiejar.New(nil)
+		s.jar = &sessionJar{CookieJar: jar, setBy: make(map[string]*url.URL)}
+		s.Jar = s.jar
+	}
+}
+
+// Cookie returns the value of a cookie in the jar, including cookies set
+// while following redirects
+func (s *Session) Cookie(name string) (string, bool) {
+	if s.jar == nil {
+		return "", false
+	}
+
+	return s.jar.Cookie(name)
+}
+
+// Client returns a copy of client using the cookie jar of the session
+func (s *Session) Client(client *http.Client) *http.Client {
+	c := *client
+	c.Jar = s.Jar
+
+	return &c
+}
+
+// sessionJar is a cookie jar remembering the url that last set each cookie,
+// so cookies can be looked up by name
+type sessionJar struct {
+	http.CookieJar
+	setBy map[string]*url.URL
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+
+	for _, c := range cookies {
+		j.setBy[c.Name] = u
+	}
+}
+
+// Cookie returns the value of a cookie, unless it has expired or been
+// removed
+func (j *sessionJar) Cookie(name string) (string, bool) {
+	u, ok := j.setBy[name]
+
+	if !ok {
+		return "", false
+	}
+
+	for _, c := range j.Cookies(u) {
+		if c.Name == name {
+			return c.Value, true
+		}
+	}
+
+	return "", false
+}
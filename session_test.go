@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSessionCookies(t *testing.T) {
+	var profileCookie, profileHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/login":
+			http.SetCookie(res, &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+		case "/profile":
+			if c, err := req.Cookie("session"); err == nil {
+				profileCookie = c.Value
+			}
+
+			profileHeader = req.Header.Get("X-Session")
+		}
+	}))
+	defer server.Close()
+
+	history := NewHistory()
+	history.Session = NewSession(SessionIteration)
+	runner := Runner{
+		Requests: &RequestCollection{Requests: []*Request{
+			&Request{Name: "login", URL: server.URL + "/login", Method: "POST"},
+			&Request{
+				Name:    "profile",
+				URL:     server.URL + "/profile",
+				Method:  "GET",
+				Headers: map[string]string{"X-Session": `{{ cookie "session" }}`},
+			},
+		}},
+		History: history,
+		Status:  NewStatus(),
+		Session: history.Session,
+	}
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if profileCookie != "abc123" || profileHeader != "abc123" {
+		t.Errorf("Session cookie was not kept between steps: %s %s", profileCookie, profileHeader)
+	}
+}
+
+func TestSessionModes(t *testing.T) {
+	cookies := []*http.Cookie{&http.Cookie{Name: "session", Value: "abc123"}}
+	u, _ := url.Parse("http://some-host/login")
+
+	for mode, kept := range map[string]bool{
+		SessionIteration: false,
+		SessionWorker:    true,
+	} {
+		session := NewSession(mode)
+		session.Jar.SetCookies(u, cookies)
+
+		if _, ok := session.Cookie("session"); !ok {
+			t.Errorf("Session %s did not keep cookies", mode)
+		}
+
+		session.Begin()
+
+		if _, ok := session.Cookie("session"); ok != kept {
+			t.Errorf("Session %s kept cookies: %t", mode, ok)
+		}
+	}
+
+	session := NewSession(SessionNone)
+
+	if _, ok := session.Cookie("session"); ok || session.Jar != nil {
+		t.Error("Session none should not keep cookies")
+	}
+}
+
+func TestSessionCookieFromRedirect(t *testing.T) {
+	var profileHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/login":
+			http.SetCookie(res, &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+			http.Redirect(res, req, "/home", http.StatusFound)
+		case "/logout":
+			http.SetCookie(res, &http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1})
+		case "/profile":
+			profileHeader = req.Header.Get("X-Session")
+		}
+	}))
+	defer server.Close()
+
+	history := NewHistory()
+	history.Session = NewSession(SessionWorker)
+	requests := &RequestCollection{Requests: []*Request{
+		&Request{Name: "login", URL: server.URL + "/login", Method: "POST"},
+		&Request{
+			Name:    "profile",
+			URL:     server.URL + "/profile",
+			Method:  "GET",
+			Headers: map[string]string{"X-Session": `{{ cookie "session" }}`},
+		},
+	}}
+	runner := Runner{Requests: requests, History: history, Status: NewStatus(), Session: history.Session}
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if profileHeader != "abc123" {
+		t.Errorf("Cookie set before a redirect was not available: %q", profileHeader)
+	}
+
+	logout := &Request{Name: "logout", URL: server.URL + "/logout", Method: "POST"}
+	requests.Requests = []*Request{logout}
+
+	if err := runner.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := history.Session.Cookie("session"); ok {
+		t.Error("Removed cookie was still available")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StageInterval is how often the number of workers is adjusted to the
+// stages
+var StageInterval = 100 * time.Millisecond
+
+// Stage ramps the number of workers linearly, from the target of the
+// previous stage, to its target over its duration
+type Stage struct {
+	Name     string        `yaml:"name"`
+	Duration time.Duration `yaml:"duration"`
+	Target   int           `yaml:"target"`
+}
+
+func (s *Stage) Validate() error {
+	if s.Duration <= 0 {
+		return errors.New("Stage duration must be positive")
+	}
+
+	if s.Target < 0 {
+		return errors.New("Stage target can't be negative")
+	}
+
+	return nil
+}
+
+// StagesTarget returns the number of workers to run after elapsed, and the
+// index of the current stage, which is -1 when every stage has passed
+func StagesTarget(stages []*Stage, elapsed time.Duration) (int, int) {
+	from := 0
+
+	for i, stage := range stages {
+		if elapsed < stage.Duration {
+			progress := float64(elapsed) / float64(stage.Duration)
+			return from + int(math.Round(float64(stage.Target-from)*progress)), i
+		}
+
+		elapsed -= stage.Duration
+		from = stage.Target
+	}
+
+	return from, -1
+}
+
+// RunStages starts and stops workers of a scenario to follow its stages.
+// Stopped workers finish their current iteration first. The scenario is
+// done when every stage has passed, or stop is closed.
+func RunStages(
+	ctx context.Context,
+	run *Run,
+	targets *Targets,
+	name string,
+	status *Status,
+	stop <-chan struct{},
+) {
+	logger := logrus.WithField("scenario", name)
+	ticker := time.NewTicker(StageInterval)
+	defer ticker.Stop()
+
+	var workers []chan struct{}
+
+	started := time.Now()
+	current := -1
+
+loop:
+	for {
+		config, _ := targets.Config()
+		scenario := config.Scenario(name)
+
+		if scenario == nil {
+			logger.Warn("Scenario was removed from targets. Closing down.")
+			break
+		}
+
+		target, stage := StagesTarget(scenario.Stages, time.Since(started))
+
+		if stage < 0 {
+			logger.Info("Every stage has passed. Closing down.")
+			break
+		}
+
+		if stage != current {
+			logger.
+				WithField("stage", scenario.Stages[stage].Name).
+				WithField("index", stage).
+				WithField("target", scenario.Stages[stage].Target).
+				Info("Entered stage")
+			current = stage
+		}
+
+		for len(workers) < target {
+			stop := make(chan struct{})
+			workers = append(workers, stop)
+
+			run.Go(func() {
+				NewWorker(targets, name, status, run.Results).Work(ctx, run, stop)
+			})
+		}
+
+		for len(workers) > target {
+			close(workers[len(workers)-1])
+			workers = workers[:len(workers)-1]
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-run.Stopping():
+			break loop
+		case <-stop:
+			break loop
+		case <-ticker.C:
+		}
+	}
+
+	for _, stop := range workers {
+		close(stop)
+	}
+}
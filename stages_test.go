@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStagesTarget(t *testing.T) {
+	stages := []*Stage{
+		{Duration: 2 * time.Minute, Target: 50},
+		{Duration: 10 * time.Minute, Target: 50},
+		{Duration: time.Minute, Target: 0},
+	}
+
+	for _, c := range []struct {
+		elapsed time.Duration
+		target  int
+		stage   int
+	}{
+		{0, 0, 0},
+		{time.Minute, 25, 0},
+		{5 * time.Minute, 50, 1},
+		{12*time.Minute + 30*time.Second, 25, 2},
+		{14 * time.Minute, 0, -1},
+	} {
+		target, stage := StagesTarget(stages, c.elapsed)
+
+		if target != c.target || stage != c.stage {
+			t.Errorf("After %s expected %d workers in stage %d, got %d in stage %d",
+				c.elapsed, c.target, c.stage, target, stage)
+		}
+	}
+}
+
+func TestRunStages(t *testing.T) {
+	StageInterval = 10 * time.Millisecond
+	defer func() { StageInterval = 100 * time.Millisecond }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{{
+		Name:   "staged",
+		Repeat: -1,
+		Stages: []*Stage{
+			{Duration: 50 * time.Millisecond, Target: 3},
+			{Duration: 200 * time.Millisecond, Target: 3},
+			{Duration: 50 * time.Millisecond, Target: 0},
+		},
+		Requests: []*Request{{Name: "index", Method: "GET", URL: server.URL}},
+	}}})
+
+	run := NewRun()
+	active := ActiveVirtualUsersGauge.WithLabelValues("staged")
+
+	run.Go(func() { RunStages(context.Background(), run, targets, "staged", NewStatus(), nil) })
+
+	time.Sleep(150 * time.Millisecond)
+
+	if gaugeValue(active) != 3 {
+		t.Errorf("Expected 3 active workers, got %f", gaugeValue(active))
+	}
+
+	if !run.Wait(4 * time.Second) {
+		t.Fatal("Timeout")
+	}
+
+	if gaugeValue(active) != 0 {
+		t.Errorf("Workers were still active after the last stage: %f", gaugeValue(active))
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"sync"
 )
 
+// Status keeps the slowest and failed responses, and the skipped requests,
+// by scenario and request name
 type Status struct {
-	Responses chan *StatusEntry         `json:"-"`
-	Mutex     sync.Mutex                `json:"-"`
-	Slowest   map[string][]*StatusEntry `json:"slowest"`
-	Errors    map[string][]*StatusEntry `json:"errors"`
+	Responses chan *StatusEntry                    `json:"-"`
+	Mutex     sync.Mutex                           `json:"-"`
+	Slowest   map[string]map[string][]*StatusEntry `json:"slowest"`
+	Errors    map[string]map[string][]*StatusEntry `json:"errors"`
+	Skipped   map[string]map[string]map[string]int `json:"skipped,omitempty"`
+	pending   sync.WaitGroup
 }
 
 var _ http.Handler = &Status{}
@@ -19,8 +23,9 @@ var _ http.Handler = &Status{}
 func NewStatus() *Status {
 	s := &Status{
 		Responses: make(chan *StatusEntry, 100),
-		Slowest:   make(map[string][]*StatusEntry),
-		Errors:    make(map[string][]*StatusEntry),
+		Slowest:   make(map[string]map[string][]*StatusEntry),
+		Errors:    make(map[string]map[string][]*StatusEntry),
+		Skipped:   make(map[string]map[string]map[string]int),
 	}
 
 	go s.loop()
@@ -33,7 +38,9 @@ func (s *Status) Handler() http.Handler {
 }
 
 func (s *Status) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.Mutex.Lock()
 	data, err := json.Marshal(s)
+	s.Mutex.Unlock()
 
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -47,10 +54,13 @@ func (s *Status) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 }
 
 func (s *Status) Record(
+	scenario string,
 	name string,
+	item int,
 	latency float64,
 	status int,
 	response string,
+	timings *Timings,
 	err error,
 ) {
 	var encoded interface{}
@@ -65,38 +75,93 @@ func (s *Status) Record(
 		errorString = err.Error()
 	}
 
-	s.Responses <- &StatusEntry{
+	entry := &StatusEntry{
+		Scenario: scenario,
 		Name:     name,
 		Latency:  latency,
 		Status:   status,
+		Timings:  timings,
 		Response: encoded,
 		Error:    errorString,
 	}
+
+	if item >= 0 {
+		entry.Item = &item
+	}
+
+	s.pending.Add(1)
+	s.Responses <- entry
+}
+
+// Skip counts a skipped request by the reason it was skipped
+func (s *Status) Skip(scenario, name, reason string) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	_, ok := s.Skipped[scenario]
+
+	if !ok {
+		s.Skipped[scenario] = make(map[string]map[string]int)
+	}
+
+	_, ok = s.Skipped[scenario][name]
+
+	if !ok {
+		s.Skipped[scenario][name] = make(map[string]int)
+	}
+
+	s.Skipped[scenario][name][reason]++
+}
+
+// Samples returns copies of the slowest and failed responses of a request
+func (s *Status) Samples(scenario, name string) ([]*StatusEntry, []*StatusEntry) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+
+	slowest := append([]*StatusEntry(nil), s.Slowest[scenario][name]...)
+	errors := append([]*StatusEntry(nil), s.Errors[scenario][name]...)
+
+	return slowest, errors
+}
+
+// Flush waits until every recorded response has been handled
+func (s *Status) Flush() {
+	s.pending.Wait()
 }
 
 func (s *Status) loop() {
 	for entry := range s.Responses {
 		s.Mutex.Lock()
 
-		_, ok := s.Errors[entry.Name]
+		_, ok := s.Errors[entry.Scenario]
+
+		if !ok {
+			s.Errors[entry.Scenario] = make(map[string][]*StatusEntry)
+			s.Slowest[entry.Scenario] = make(map[string][]*StatusEntry)
+		}
+
+		scenarioErrors := s.Errors[entry.Scenario]
+		scenarioSlowest := s.Slowest[entry.Scenario]
+
+		_, ok = scenarioErrors[entry.Name]
 
 		if !ok {
-			s.Errors[entry.Name] = make([]*StatusEntry, 0)
+			scenarioErrors[entry.Name] = make([]*StatusEntry, 0)
 		}
 
 		if len(entry.Error) > 0 {
-			errors := append([]*StatusEntry{entry}, s.Errors[entry.Name]...)
+			errors := append([]*StatusEntry{entry}, scenarioErrors[entry.Name]...)
 
 			if len(errors) > 3 {
-				s.Errors[entry.Name] = errors[:2]
+				scenarioErrors[entry.Name] = errors[:2]
 			} else {
-				s.Errors[entry.Name] = errors
+				scenarioErrors[entry.Name] = errors
 			}
 		} else {
-			slowest, ok := s.Slowest[entry.Name]
+			slowest, ok := scenarioSlowest[entry.Name]
 
 			if !ok {
-				s.Slowest[entry.Name] = []*StatusEntry{entry}
+				scenarioSlowest[entry.Name] = []*StatusEntry{entry}
 			} else {
 				for i, e := range slowest {
 					if e.Latency < entry.Latency {
@@ -109,21 +174,25 @@ func (s *Status) loop() {
 				}
 
 				if len(slowest) > 3 {
-					s.Slowest[entry.Name] = slowest[:2]
+					scenarioSlowest[entry.Name] = slowest[:2]
 				} else {
-					s.Slowest[entry.Name] = slowest
+					scenarioSlowest[entry.Name] = slowest
 				}
 			}
 		}
 
 		s.Mutex.Unlock()
+		s.pending.Done()
 	}
 }
 
 type StatusEntry struct {
+	Scenario string      `json:"-"`
 	Name     string      `json:"-"`
+	Item     *int        `json:"item,omitempty"`
 	Latency  float64     `json:"latency"`
 	Status   int         `json:"status"`
+	Timings  *Timings    `json:"timings,omitempty"`
 	Response interface{} `json:"response"`
 	Error    string      `json:"error,omitempty"`
 }
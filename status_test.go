@@ -23,7 +23,7 @@ func (r *ResponseMock) Write(body []byte) (int, error) {
 
 func TestRecordAndServe(t *testing.T) {
 	called := 0
-	fixture := `{"slowest":{"a request":[{"latency":123.3,"status":200,"response":{"ok":"yes?"}},{"latency":1.123,"status":200,"response":{"ok":"yes?"}}],"another request":[{"latency":333,"status":200,"response":{"ok":"yes?"}},{"latency":93.1,"status":200,"response":{"ok":"yes?"}},{"latency":12.3,"status":200,"response":{"ok":"yes?"}}]},"errors":{"a request":[],"another request":[{"latency":12.3,"status":200,"response":{"ok":"yes?"},"error":"some error"},{"latency":12.3,"status":200,"response":{"ok":"yes?"},"error":"some other error"}]}}`
+	fixture := `{"slowest":{"browse":{"a request":[{"latency":123.3,"status":200,"response":{"ok":"yes?"}},{"latency":1.123,"status":200,"response":{"ok":"yes?"}}],"another request":[{"latency":333,"status":200,"response":{"ok":"yes?"}},{"latency":93.1,"status":200,"response":{"ok":"yes?"}},{"latency":12.3,"status":200,"response":{"ok":"yes?"}}]}},"errors":{"browse":{"a request":[],"another request":[{"latency":12.3,"status":200,"response":{"ok":"yes?"},"error":"some error"},{"latency":12.3,"status":200,"response":{"ok":"yes?"},"error":"some other error"}]}}}`
 	res := ResponseMock{
 		WriteCallback: func(body []byte) (int, error) {
 			called++
@@ -38,16 +38,16 @@ func TestRecordAndServe(t *testing.T) {
 
 	status := NewStatus()
 
-	status.Record("a request", 1.123, 200, `{"ok":"yes?"}`, nil)
-	status.Record("a request", 123.3, 200, `{"ok":"yes?"}`, nil)
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, nil)
-	status.Record("another request", 93.1, 200, `{"ok":"yes?"}`, nil)
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, nil)
-	status.Record("another request", 333.0, 200, `{"ok":"yes?"}`, nil)
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, errors.New("error gone"))
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, errors.New("an error"))
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, errors.New("some other error"))
-	status.Record("another request", 12.3, 200, `{"ok":"yes?"}`, errors.New("some error"))
+	status.Record("browse", "a request", -1, 1.123, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "a request", -1, 123.3, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "another request", -1, 93.1, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "another request", -1, 333.0, 200, `{"ok":"yes?"}`, nil, nil)
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, errors.New("error gone"))
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, errors.New("an error"))
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, errors.New("some other error"))
+	status.Record("browse", "another request", -1, 12.3, 200, `{"ok":"yes?"}`, nil, errors.New("some error"))
 
 	time.Sleep(time.Millisecond * 100)
 
@@ -61,3 +61,23 @@ func TestRecordAndServe(t *testing.T) {
 		t.Error("Response writer Write wasn't called once")
 	}
 }
+
+func TestRecordByScenario(t *testing.T) {
+	status := NewStatus()
+
+	status.Record("browse", "start", -1, 0.1, 200, "", nil, nil)
+	status.Record("checkout", "start", -1, 0.2, 500, "", nil, errors.New("failed"))
+	status.Skip("checkout", "start", "condition")
+	status.Flush()
+
+	browseSlowest, browseErrors := status.Samples("browse", "start")
+	checkoutSlowest, checkoutErrors := status.Samples("checkout", "start")
+
+	if len(browseSlowest) != 1 || len(browseErrors) != 0 || len(checkoutSlowest) != 0 || len(checkoutErrors) != 1 {
+		t.Error("Requests of the same name in different scenarios were merged")
+	}
+
+	if status.Skipped["checkout"]["start"]["condition"] != 1 || status.Skipped["browse"] != nil {
+		t.Errorf("Skipped request was not recorded by scenario: %v", status.Skipped)
+	}
+}
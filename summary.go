@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// SummaryLatencySamples is the number of latencies kept for every
+	// request to compute percentiles from. Longer runs are sampled.
+	SummaryLatencySamples = 100000
+
+	// SummaryErrors is the number of distinct error messages kept for
+	// every request. The rest are counted as other errors.
+	SummaryErrors = 10
+
+	SummaryOtherErrors = "other errors"
+)
+
+// Summary aggregates the results of a run, per request, to report at the
+// end of it
+type Summary struct {
+	Started  time.Time
+	Requests map[string]*RequestSummary
+	mutex    sync.Mutex
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		Started:  time.Now(),
+		Requests: make(map[string]*RequestSummary),
+	}
+}
+
+func (s *Summary) Handle(result *Result) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := result.Scenario + "\x00" + result.Name
+	request, ok := s.Requests[key]
+
+	if !ok {
+		request = &RequestSummary{
+			Scenario: result.Scenario,
+			Name:     result.Name,
+			Statuses: make(map[string]int),
+			Errors:   make(map[string]int),
+		}
+		s.Requests[key] = request
+	}
+
+	request.Add(result)
+}
+
+// Report returns the summaries of every request, sorted by scenario and name
+func (s *Summary) Report() *SummaryReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &SummaryReport{
+		Duration: time.Since(s.Started).Seconds(),
+		Requests: make([]*RequestSummary, 0, len(s.Requests)),
+	}
+
+	for _, request := range s.Requests {
+		request.Latency = request.latencies.Summarize()
+		report.Requests = append(report.Requests, request)
+	}
+
+	sort.Slice(report.Requests, func(i, j int) bool {
+		a, b := report.Requests[i], report.Requests[j]
+
+		if a.Scenario != b.Scenario {
+			return a.Scenario < b.Scenario
+		}
+
+		return a.Name < b.Name
+	})
+
+	return report
+}
+
+// Select returns the summary of every request matching scenario and name,
+// where empty matches all, or nil when there are no such results
+func (s *Summary) Select(scenario, name string) *RequestSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var selected *RequestSummary
+
+	for _, request := range s.Requests {
+		if scenario != "" && request.Scenario != scenario || name != "" && request.Name != name {
+			continue
+		}
+
+		if selected == nil {
+			selected = &RequestSummary{
+				Scenario: scenario,
+				Name:     name,
+				Statuses: make(map[string]int),
+				Errors:   make(map[string]int),
+			}
+		}
+
+		selected.Merge(request)
+	}
+
+	if selected != nil {
+		selected.Latency = selected.latencies.Summarize()
+	}
+
+	return selected
+}
+
+type SummaryReport struct {
+	Duration   float64            `json:"duration"`
+	Requests   []*RequestSummary  `json:"requests"`
+	Thresholds []*ThresholdResult `json:"thresholds,omitempty"`
+}
+
+// Print writes the report as a table, followed by the errors of every
+// request and the outcome of the thresholds
+func (r *SummaryReport) Print(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(table, "Run ended after %s\n\n", formatSeconds(r.Duration))
+	fmt.Fprintln(table, "SCENARIO\tNAME\tREQUESTS\t2XX\t4XX\t5XX\tERROR\tTIMEOUT\tEXPECT OK\tEXPECT FAILED\t"+
+		"MIN\tAVG\tP50\tP90\tP95\tP99\tMAX")
+
+	for _, request := range r.Requests {
+		l := request.Latency
+
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			request.Scenario,
+			request.Name,
+			request.Count,
+			request.Statuses["2xx"],
+			request.Statuses["4xx"],
+			request.Statuses["5xx"],
+			request.Statuses["error"],
+			request.Statuses["timeout"],
+			request.Expectations.Passed,
+			request.Expectations.Failed,
+			formatSeconds(l.Min),
+			formatSeconds(l.Avg),
+			formatSeconds(l.P50),
+			formatSeconds(l.P90),
+			formatSeconds(l.P95),
+			formatSeconds(l.P99),
+			formatSeconds(l.Max),
+		)
+	}
+
+	err := table.Flush()
+
+	if err != nil {
+		return err
+	}
+
+	for _, request := range r.Requests {
+		if len(request.Errors) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(writer, "\nErrors of %s %s:\n", request.Scenario, request.Name)
+
+		for _, message := range sortedErrors(request.Errors) {
+			fmt.Fprintf(writer, "  %d  %s\n", request.Errors[message], message)
+		}
+	}
+
+	r.PrintThresholds(writer)
+
+	return nil
+}
+
+// PrintThresholds writes the outcome of the thresholds, if there are any
+func (r *SummaryReport) PrintThresholds(writer io.Writer) {
+	if len(r.Thresholds) == 0 {
+		return
+	}
+
+	fmt.Fprintln(writer, "\nThresholds:")
+
+	for _, threshold := range r.Thresholds {
+		fmt.Fprintf(writer, "  %s\n", threshold)
+	}
+}
+
+// Failed tells whether any threshold failed
+func (r *SummaryReport) Failed() bool {
+	for _, threshold := range r.Thresholds {
+		if !threshold.Passed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *SummaryReport) WriteJSON(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+type RequestSummary struct {
+	Scenario     string             `json:"scenario"`
+	Name         string             `json:"name"`
+	Count        int                `json:"count"`
+	Statuses     map[string]int     `json:"statuses"`
+	Expectations ExpectationSummary `json:"expectations"`
+	Errors       map[string]int     `json:"errors"`
+	Latency      LatencySummary     `json:"latency"`
+	latencies    Latencies
+}
+
+func (s *RequestSummary) Add(result *Result) {
+	s.Count++
+	s.Statuses[result.Status]++
+
+	if result.Error != "" {
+		addCapped(s.Errors, result.Error)
+		return
+	}
+
+	s.latencies.Add(result.Latency)
+
+	if !result.Expected {
+		return
+	}
+
+	if result.Expectation == "" {
+		s.Expectations.Passed++
+	} else {
+		s.Expectations.Failed++
+	}
+}
+
+// Merge adds the counts and latencies of other
+func (s *RequestSummary) Merge(other *RequestSummary) {
+	s.Count += other.Count
+	s.Expectations.Passed += other.Expectations.Passed
+	s.Expectations.Failed += other.Expectations.Failed
+
+	for status, count := range other.Statuses {
+		s.Statuses[status] += count
+	}
+
+	for message, count := range other.Errors {
+		s.Errors[message] += count
+	}
+
+	s.latencies.Merge(&other.latencies)
+}
+
+type ExpectationSummary struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+type LatencySummary struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Latencies keeps the exact min, max and average of latencies in seconds,
+// and a random sample of Size of them, or SummaryLatencySamples when 0, for
+// percentiles
+type Latencies struct {
+	Count   int
+	Sum     float64
+	Min     float64
+	Max     float64
+	Size    int
+	samples []float64
+}
+
+func (l *Latencies) Add(latency float64) {
+	l.Count++
+	l.Sum += latency
+
+	if l.Count == 1 || latency < l.Min {
+		l.Min = latency
+	}
+
+	if latency > l.Max {
+		l.Max = latency
+	}
+
+	if len(l.samples) < l.size() {
+		l.samples = append(l.samples, latency)
+		return
+	}
+
+	// Reservoir sampling keeps every latency with the same probability
+	if i := rand.Intn(l.Count); i < l.size() {
+		l.samples[i] = latency
+	}
+}
+
+// Merge adds the latencies of other. Samples of long runs are kept in
+// proportion to the number of latencies of each.
+func (l *Latencies) Merge(other *Latencies) {
+	if other.Count == 0 {
+		return
+	}
+
+	if l.Count == 0 || other.Min < l.Min {
+		l.Min = other.Min
+	}
+
+	if other.Max > l.Max {
+		l.Max = other.Max
+	}
+
+	total := l.Count + other.Count
+	samples := append(append([]float64(nil), l.samples...), other.samples...)
+
+	if len(samples) > l.size() {
+		keep := int(float64(l.size()) * float64(l.Count) / float64(total))
+
+		if keep > len(l.samples) {
+			keep = len(l.samples)
+		}
+
+		rest := l.size() - keep
+
+		if rest > len(other.samples) {
+			rest = len(other.samples)
+		}
+
+		samples = append(append([]float64(nil), l.samples[:keep]...), other.samples[:rest]...)
+	}
+
+	l.Count = total
+	l.Sum += other.Sum
+	l.samples = samples
+}
+
+func (l *Latencies) size() int {
+	if l.Size > 0 {
+		return l.Size
+	}
+
+	return SummaryLatencySamples
+}
+
+func (l *Latencies) Summarize() LatencySummary {
+	if l.Count == 0 {
+		return LatencySummary{}
+	}
+
+	sorted := append([]float64(nil), l.samples...)
+	sort.Float64s(sorted)
+
+	return LatencySummary{
+		Min: l.Min,
+		Avg: l.Sum / float64(l.Count),
+		P50: percentile(sorted, 0.5),
+		P90: percentile(sorted, 0.9),
+		P95: percentile(sorted, 0.95),
+		P99: percentile(sorted, 0.99),
+		Max: l.Max,
+	}
+}
+
+// percentile returns the nearest rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(10 * time.Microsecond).String()
+}
+
+// sortedErrors returns the error messages, the most frequent first
+func sortedErrors(errors map[string]int) []string {
+	messages := make([]string, 0, len(errors))
+
+	for message := range errors {
+		messages = append(messages, message)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		if errors[messages[i]] != errors[messages[j]] {
+			return errors[messages[i]] > errors[messages[j]]
+		}
+
+		return messages[i] < messages[j]
+	})
+
+	return messages
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummaryReport(t *testing.T) {
+	summary := NewSummary()
+
+	for i := 1; i <= 100; i++ {
+		summary.Handle(&Result{
+			Scenario:   "browse",
+			Name:       "start",
+			Status:     "2xx",
+			StatusCode: 200,
+			Latency:    float64(i) / 1000,
+			Expected:   true,
+		})
+	}
+
+	summary.Handle(&Result{
+		Scenario:    "browse",
+		Name:        "start",
+		Status:      "5xx",
+		StatusCode:  503,
+		Latency:     0.5,
+		Expected:    true,
+		Expectation: "Status code 503, did not match 2..",
+	})
+
+	for i := 0; i < SummaryErrors+2; i++ {
+		summary.Handle(&Result{
+			Scenario: "browse",
+			Name:     "search",
+			Status:   "error",
+			Error:    fmt.Sprintf("Failed %d", i),
+		})
+	}
+
+	report := summary.Report()
+
+	if len(report.Requests) != 2 || report.Requests[0].Name != "search" {
+		t.Fatalf("Report did not contain sorted requests: %+v", report.Requests)
+	}
+
+	start := report.Requests[1]
+
+	if start.Count != 101 || start.Statuses["2xx"] != 100 || start.Statuses["5xx"] != 1 {
+		t.Errorf("Statuses were not counted: %+v", start.Statuses)
+	}
+
+	if start.Expectations.Passed != 100 || start.Expectations.Failed != 1 {
+		t.Errorf("Expectations were not counted: %+v", start.Expectations)
+	}
+
+	l := start.Latency
+
+	if l.Min != 0.001 || l.Max != 0.5 || l.P50 != 0.051 || l.P99 != 0.1 {
+		t.Errorf("Latencies were not summarized: %+v", l)
+	}
+
+	search := report.Requests[0]
+
+	if len(search.Errors) != SummaryErrors+1 || search.Errors[SummaryOtherErrors] != 2 {
+		t.Errorf("Errors were not capped: %v", search.Errors)
+	}
+
+	var out bytes.Buffer
+
+	if err := report.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "Errors of browse search:") ||
+		!strings.Contains(out.String(), "500ms") {
+		t.Errorf("Printed summary is missing content:\n%s", out.String())
+	}
+}
+
+func TestSummaryWriteJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	summary := NewSummary()
+	summary.Handle(&Result{Scenario: "default", Name: "start", Status: "2xx", Latency: 0.1})
+
+	filename := filepath.Join(dir, "summary.json")
+
+	if err := summary.Report().WriteJSON(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report SummaryReport
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Requests) != 1 || report.Requests[0].Latency.P95 != 0.1 {
+		t.Errorf("Written summary does not match: %s", data)
+	}
+}
+
+func TestSummarySelect(t *testing.T) {
+	summary := NewSummary()
+	summary.Handle(&Result{Scenario: "browse", Name: "start", Status: "2xx", Latency: 0.1})
+	summary.Handle(&Result{Scenario: "browse", Name: "search", Status: "2xx", Latency: 0.3})
+	summary.Handle(&Result{Scenario: "checkout", Name: "start", Status: "4xx", Latency: 0.2})
+
+	start := summary.Select("", "start")
+
+	if start.Count != 2 || start.Statuses["4xx"] != 1 || start.Latency.Max != 0.2 {
+		t.Errorf("Requests of every scenario were not merged: %+v", start)
+	}
+
+	browse := summary.Select("browse", "")
+
+	if browse.Count != 2 || browse.Latency.Min != 0.1 || browse.Latency.Max != 0.3 {
+		t.Errorf("Requests of the scenario were not merged: %+v", browse)
+	}
+
+	if summary.Select("browse", "checkout") != nil {
+		t.Error("Selected requests that were not sent")
+	}
+}
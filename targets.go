@@ -0,0 +1,228 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Targets struct {
+	Filename string
+	mutex    sync.RWMutex
+	config   *Config
+	version  int
+	modified time.Time
+	size     int64
+}
+
+func NewTargets(filename string) *Targets {
+	return &Targets{
+		Filename: filename,
+	}
+}
+
+func (t *Targets) Config() (*Config, int) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.config, t.version
+}
+
+// Set replaces the config, and closes the idle connections of the previous
+// one. Workers still using it open new connections until they pick up the
+// new config.
+func (t *Targets) Set(config *Config) {
+	config.Init()
+
+	t.mutex.Lock()
+	previous := t.config
+	t.config = config
+	t.version++
+	t.mutex.Unlock()
+
+	if previous != nil {
+		previous.CloseClients()
+	}
+}
+
+func (t *Targets) Load() error {
+	t.stat()
+
+	config, err := t.read()
+
+	if err != nil {
+		TargetsFileError.Inc()
+		ConfigReloadSuccessGauge.Set(0)
+
+		return err
+	}
+
+	InitScenarioMetrics(config)
+	t.Set(config)
+
+	ConfigReloadSuccessGauge.Set(1)
+	ConfigReloadTimestampGauge.SetToCurrentTime()
+
+	return nil
+}
+
+func (t *Targets) Changed() bool {
+	info, err := os.Stat(t.Filename)
+
+	if err != nil {
+		return false
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return !info.ModTime().Equal(t.modified) || info.Size() != t.size
+}
+
+func (t *Targets) Watch(interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	watchLogger := logrus.
+		WithField("targets", t.Filename).
+		WithField("interval", interval.String())
+
+	watchLogger.Info("Watching targets file")
+
+	for {
+		select {
+		case <-hup:
+			watchLogger.Info("Got SIGHUP, reloading targets file")
+		case <-tick:
+			if !t.Changed() {
+				continue
+			}
+
+			watchLogger.Info("Targets file changed, reloading")
+		}
+
+		err := t.Load()
+
+		if err != nil {
+			watchLogger.
+				WithError(err).
+				Error("Could not reload targets file, keeping previous targets")
+			continue
+		}
+
+		config, version := t.Config()
+
+		watchLogger.
+			WithField("scenarios", len(config.Scenarios)).
+			WithField("version", version).
+			Info("Reloaded targets file")
+	}
+}
+
+func (t *Targets) read() (*Config, error) {
+	data, err := ioutil.ReadFile(t.Filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, errs := ValidateTargets(t.Filename, data)
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return config, nil
+}
+
+func (t *Targets) stat() {
+	info, err := os.Stat(t.Filename)
+
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.modified = info.ModTime()
+	t.size = info.Size()
+}
+
+func InitScenarioMetrics(config *Config) {
+	ScenarioLabelsGauge.Reset()
+
+	for _, s := range config.Scenarios {
+		for k, v := range s.Labels {
+			ScenarioLabelsGauge.WithLabelValues(s.Name, k, v).Set(1)
+		}
+
+		IterationsCounter.WithLabelValues(s.Name)
+		IterationDurationHistogram.WithLabelValues(s.Name)
+		ActiveVirtualUsersGauge.WithLabelValues(s.Name)
+
+		if s.Rate != "" {
+			IterationsDroppedCounter.WithLabelValues(s.Name)
+		}
+
+		for _, r := range s.Requests {
+			RequestStatusCounter.WithLabelValues(s.Name, r.GetName(), "error")
+			RequestStatusCounter.WithLabelValues(s.Name, r.GetName(), "timeout")
+			RequestSkippedCounter.WithLabelValues(s.Name, r.GetName(), "failure")
+			RequestSkippedCounter.WithLabelValues(s.Name, r.GetName(), "condition")
+
+			if r.Foreach != nil {
+				RequestSkippedCounter.WithLabelValues(s.Name, r.GetName(), "empty")
+			}
+
+			for _, part := range r.Expect.Parts() {
+				ExpectationsCounter.WithLabelValues(s.Name, r.GetName(), part, "passed")
+				ExpectationsCounter.WithLabelValues(s.Name, r.GetName(), part, "failed")
+			}
+
+			for _, a := range r.Expect.JSON {
+				if a != nil {
+					JSONAssertionsCounter.WithLabelValues(s.Name, r.GetName(), a.GetName(), "passed")
+					JSONAssertionsCounter.WithLabelValues(s.Name, r.GetName(), a.GetName(), "failed")
+				}
+			}
+
+			for _, phase := range []string{"ttfb", "transfer"} {
+				RequestPhaseHistogram.WithLabelValues(s.Name, r.GetName(), phase)
+			}
+
+			for _, status := range []string{"2xx", "4xx", "5xx"} {
+				RequestStatusCounter.WithLabelValues(s.Name, r.GetName(), status)
+				RequestLatencyHistogram.WithLabelValues(s.Name, r.GetName(), status)
+
+				if LatencySummaryEnabled {
+					RequestLatencySummary.WithLabelValues(s.Name, r.GetName(), status)
+				}
+			}
+		}
+	}
+}
+
+func CloneRequests(requests []*Request) []*Request {
+	clones := make([]*Request, len(requests))
+
+	for i, r := range requests {
+		clone := *r
+		clones[i] = &clone
+	}
+
+	return clones
+}
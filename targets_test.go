@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeTargets(t *testing.T, filename, content string) {
+	err := ioutil.WriteFile(filename, []byte(content), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTargetsLoadAndReload(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "*")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(tmpfile.Name())
+
+	writeTargets(t, tmpfile.Name(), `
+- name: request-1
+  url: http://some-url-1
+`)
+
+	targets := NewTargets(tmpfile.Name())
+
+	if err := targets.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, version := targets.Config()
+	requests := config.Scenario(DefaultScenarioName).Requests
+
+	if len(requests) != 1 || requests[0].Name != "request-1" || version != 1 {
+		t.Errorf("Targets were not loaded: %d requests, version %d", len(requests), version)
+	}
+
+	if targets.Changed() {
+		t.Error("Targets should not be changed right after load")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	writeTargets(t, tmpfile.Name(), `
+- name: request-1
+  url: http://some-url-1
+- name: request-2
+  url: http://some-url-2
+`)
+
+	if !targets.Changed() {
+		t.Error("Targets should be changed after write")
+	}
+
+	if err := targets.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, version = targets.Config()
+	requests = config.Scenario(DefaultScenarioName).Requests
+
+	if len(requests) != 2 || version != 2 {
+		t.Errorf("Targets were not reloaded: %d requests, version %d", len(requests), version)
+	}
+}
+
+func TestTargetsKeepPreviousOnInvalid(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "*")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(tmpfile.Name())
+
+	writeTargets(t, tmpfile.Name(), `
+- name: request-1
+  url: http://some-url-1
+`)
+
+	targets := NewTargets(tmpfile.Name())
+
+	if err := targets.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"- name: [broken", "- name: no url"} {
+		writeTargets(t, tmpfile.Name(), content)
+
+		if err := targets.Load(); err == nil {
+			t.Errorf("Load should fail on invalid targets: %s", content)
+		}
+	}
+
+	config, version := targets.Config()
+	requests := config.Scenario(DefaultScenarioName).Requests
+
+	if len(requests) != 1 || requests[0].Name != "request-1" || version != 1 {
+		t.Error("Previous targets were not kept")
+	}
+}
+
+func TestCloneRequests(t *testing.T) {
+	requests := []*Request{&Request{Name: "1"}}
+	clones := CloneRequests(requests)
+
+	clones[0].SetParser(&FakeParser{})
+
+	if clones[0] == requests[0] || requests[0].Parser != nil {
+		t.Error("Cloned requests share state with originals")
+	}
+}
+
+func TestTargetsSetClosesClients(t *testing.T) {
+	var closed int32
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			atomic.AddInt32(&closed, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client, err := (&ClientConfig{}).Build("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets := NewTargets("")
+	targets.Set(&Config{Scenarios: []*Scenario{{
+		Name:     "clients",
+		Requests: []*Request{{Name: "index", URL: server.URL, HTTPClient: client}},
+	}}})
+
+	res, err := client.Get(server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	targets.Set(&Config{Scenarios: []*Scenario{{Name: "clients"}}})
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Error("Idle connection of the previous client was not closed")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	ThinkTimeConstant    = "constant"
+	ThinkTimeUniform     = "uniform"
+	ThinkTimeNormal      = "normal"
+	ThinkTimeExponential = "exponential"
+)
+
+// ThinkTime is the pause after a request, drawn from a distribution to
+// simulate users reading and typing
+type ThinkTime struct {
+	Distribution string        `yaml:"distribution"`
+	Duration     time.Duration `yaml:"duration"`
+	Min          time.Duration `yaml:"min"`
+	Max          time.Duration `yaml:"max"`
+	Mean         time.Duration `yaml:"mean"`
+	StdDev       time.Duration `yaml:"stddev"`
+}
+
+// UnmarshalYAML accepts both a plain duration, which is a constant think
+// time, and a mapping with a distribution.
+func (t *ThinkTime) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var duration time.Duration
+
+	if unmarshal(&duration) == nil {
+		*t = ThinkTime{Distribution: ThinkTimeConstant, Duration: duration}
+		return nil
+	}
+
+	type plain ThinkTime
+
+	return unmarshal((*plain)(t))
+}
+
+func (t *ThinkTime) Validate() error {
+	if t.Duration < 0 || t.Min < 0 || t.Max < 0 || t.Mean < 0 || t.StdDev < 0 {
+		return errors.New("Think time can't be negative")
+	}
+
+	switch t.Distribution {
+	case "", ThinkTimeConstant:
+	case ThinkTimeUniform:
+		if t.Max < t.Min {
+			return errors.New("Think time max can't be less than min")
+		}
+	case ThinkTimeNormal:
+		if t.Mean == 0 {
+			return errors.New("Think time with normal distribution must set mean")
+		}
+	case ThinkTimeExponential:
+		if t.Mean == 0 {
+			return errors.New("Think time with exponential distribution must set mean")
+		}
+	default:
+		return fmt.Errorf(
+			"Think time distribution must be constant, uniform, normal or exponential, not %q",
+			t.Distribution,
+		)
+	}
+
+	return nil
+}
+
+// Sample returns a think time drawn from the distribution. Normal and
+// exponential think times are capped by max, when set, and never negative.
+func (t *ThinkTime) Sample() time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	var duration time.Duration
+
+	switch t.Distribution {
+	case ThinkTimeUniform:
+		duration = t.Min
+
+		if t.Max > t.Min {
+			duration += time.Duration(rand.Int63n(int64(t.Max-t.Min) + 1))
+		}
+
+		return duration
+	case ThinkTimeNormal:
+		duration = t.Mean + time.Duration(rand.NormFloat64()*float64(t.StdDev))
+	case ThinkTimeExponential:
+		duration = time.Duration(rand.ExpFloat64() * float64(t.Mean))
+	default:
+		return t.Duration
+	}
+
+	if t.Max > 0 && duration > t.Max {
+		duration = t.Max
+	}
+
+	if duration < 0 {
+		duration = 0
+	}
+
+	return duration
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestThinkTimeUnmarshal(t *testing.T) {
+	var scenario struct {
+		Constant ThinkTime `yaml:"constant"`
+		Normal   ThinkTime `yaml:"normal"`
+	}
+
+	err := yaml.UnmarshalStrict([]byte(`
+constant: 1500ms
+normal:
+  distribution: normal
+  mean: 2s
+  stddev: 500ms
+`), &scenario)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scenario.Constant.Distribution != ThinkTimeConstant || scenario.Constant.Duration != 1500*time.Millisecond {
+		t.Errorf("Duration was not a constant think time: %+v", scenario.Constant)
+	}
+
+	if scenario.Normal.Mean != 2*time.Second || scenario.Normal.StdDev != 500*time.Millisecond {
+		t.Errorf("Normal think time does not match: %+v", scenario.Normal)
+	}
+}
+
+func TestThinkTimeValidate(t *testing.T) {
+	for _, think := range []ThinkTime{
+		{Distribution: "gaussian"},
+		{Distribution: ThinkTimeUniform, Min: 2 * time.Second, Max: time.Second},
+		{Distribution: ThinkTimeNormal, StdDev: time.Second},
+		{Distribution: ThinkTimeExponential},
+		{Duration: -time.Second},
+	} {
+		if think.Validate() == nil {
+			t.Errorf("Think time should not be valid: %+v", think)
+		}
+	}
+}
+
+func TestThinkTimeSample(t *testing.T) {
+	uniform := &ThinkTime{Distribution: ThinkTimeUniform, Min: time.Second, Max: 2 * time.Second}
+	normal := &ThinkTime{Distribution: ThinkTimeNormal, Mean: time.Second, StdDev: time.Second, Max: 2 * time.Second}
+	exponential := &ThinkTime{Distribution: ThinkTimeExponential, Mean: time.Second, Max: 3 * time.Second}
+
+	for i := 0; i < 1000; i++ {
+		if d := uniform.Sample(); d < time.Second || d > 2*time.Second {
+			t.Fatalf("Uniform think time out of range: %s", d)
+		}
+
+		if d := normal.Sample(); d < 0 || d > 2*time.Second {
+			t.Fatalf("Normal think time out of range: %s", d)
+		}
+
+		if d := exponential.Sample(); d < 0 || d > 3*time.Second {
+			t.Fatalf("Exponential think time out of range: %s", d)
+		}
+	}
+
+	var none *ThinkTime
+
+	if none.Sample() != 0 {
+		t.Error("Missing think time should be zero")
+	}
+}
+
+func TestThink(t *testing.T) {
+	then := time.Now()
+
+	if err := think(context.Background(), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if time.Since(then) < 50*time.Millisecond {
+		t.Error("Think did not pause")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := think(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("Think was not cancelled: %v", err)
+	}
+}
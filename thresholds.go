@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ExitThresholds is the exit code when a threshold has failed
+const ExitThresholds = 99
+
+const thresholdNoResults = "no results"
+
+// ThresholdInterval is how often thresholds aborting the run are checked
+var ThresholdInterval = 5 * time.Second
+
+var thresholdOperators = []string{"<=", ">=", "<", ">"}
+
+// Threshold is a check of the results of a run, like p95 < 300ms, for the
+// requests matching scenario and request, where empty matches all
+type Threshold struct {
+	Scenario string        `yaml:"scenario"`
+	Request  string        `yaml:"request"`
+	Check    string        `yaml:"check"`
+	Abort    bool          `yaml:"abort"`
+	Delay    time.Duration `yaml:"delay"`
+}
+
+// Parse splits the check into a metric, an operator and a value. Latencies
+// are in seconds and rates are fractions.
+func (t *Threshold) Parse() (string, string, float64, error) {
+	fields := strings.Fields(t.Check)
+
+	if len(fields) != 3 {
+		return "", "", 0, fmt.Errorf("Threshold check must be like p95 < 300ms, not %q", t.Check)
+	}
+
+	metric, operator, raw := fields[0], fields[1], fields[2]
+
+	if !containsString(thresholdOperators, operator) {
+		return "", "", 0, fmt.Errorf("Threshold operator must be <, <=, > or >=, not %q", operator)
+	}
+
+	var value float64
+	var err error
+
+	switch metric {
+	case "min", "avg", "p50", "p90", "p95", "p99", "max":
+		var duration time.Duration
+		duration, err = time.ParseDuration(raw)
+		value = duration.Seconds()
+	case "error_rate", "expectation_pass_rate":
+		if strings.HasSuffix(raw, "%") {
+			value, err = strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+			value /= 100
+		} else {
+			value, err = strconv.ParseFloat(raw, 64)
+		}
+	case "requests":
+		value, err = strconv.ParseFloat(raw, 64)
+	default:
+		return "", "", 0, fmt.Errorf(
+			"Threshold metric must be min, avg, p50, p90, p95, p99, max, error_rate, expectation_pass_rate or requests, not %q",
+			metric,
+		)
+	}
+
+	if err != nil {
+		return "", "", 0, fmt.Errorf("Threshold value of %s can't be %q", metric, raw)
+	}
+
+	return metric, operator, value, nil
+}
+
+func (t *Threshold) Validate() error {
+	_, _, _, err := t.Parse()
+
+	if err != nil {
+		return err
+	}
+
+	if t.Delay < 0 {
+		return errors.New("Threshold delay can't be negative")
+	}
+
+	return nil
+}
+
+// Evaluate checks the threshold against the results of the run. Thresholds
+// without any matching results fail.
+func (t *Threshold) Evaluate(summary *Summary) *ThresholdResult {
+	result := &ThresholdResult{Threshold: t}
+	metric, operator, expected, err := t.Parse()
+
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	selected := summary.Select(t.Scenario, t.Request)
+
+	if selected == nil {
+		result.Message = thresholdNoResults
+		return result
+	}
+
+	value := thresholdValue(selected, metric)
+
+	switch operator {
+	case "<":
+		result.Passed = value < expected
+	case "<=":
+		result.Passed = value <= expected
+	case ">":
+		result.Passed = value > expected
+	case ">=":
+		result.Passed = value >= expected
+	}
+
+	result.Value = value
+	result.Message = fmt.Sprintf("%s = %s", metric, formatThresholdValue(metric, value))
+
+	return result
+}
+
+func (t *Threshold) String() string {
+	var target []string
+
+	if t.Scenario != "" {
+		target = append(target, t.Scenario)
+	}
+
+	if t.Request != "" {
+		target = append(target, t.Request)
+	}
+
+	if len(target) == 0 {
+		return t.Check
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(target, " "), t.Check)
+}
+
+type ThresholdResult struct {
+	Threshold *Threshold `json:"threshold"`
+	Passed    bool       `json:"passed"`
+	Value     float64    `json:"value"`
+	Message   string     `json:"message"`
+}
+
+func (r *ThresholdResult) String() string {
+	outcome := "ok  "
+
+	if !r.Passed {
+		outcome = "FAIL"
+	}
+
+	return fmt.Sprintf("%s  %s (%s)", outcome, r.Threshold, r.Message)
+}
+
+func EvaluateThresholds(thresholds []*Threshold, summary *Summary) []*ThresholdResult {
+	results := make([]*ThresholdResult, 0, len(thresholds))
+
+	for _, t := range thresholds {
+		if t != nil {
+			results = append(results, t.Evaluate(summary))
+		}
+	}
+
+	return results
+}
+
+// WatchThresholds checks the thresholds set to abort every
+// ThresholdInterval, once their delay has passed, until the run is done. A
+// failing threshold stops the run, and is returned.
+func WatchThresholds(run *Run, targets *Targets, summary *Summary) *ThresholdResult {
+	ticker := time.NewTicker(ThresholdInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-run.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		config, _ := targets.Config()
+
+		if config == nil {
+			continue
+		}
+
+		for _, t := range config.Thresholds {
+			if t == nil || !t.Abort || time.Since(summary.Started) < t.Delay {
+				continue
+			}
+
+			result := t.Evaluate(summary)
+
+			// Requests may not have been sent yet
+			if result.Passed || result.Message == thresholdNoResults {
+				continue
+			}
+
+			logrus.
+				WithField("threshold", t.String()).
+				WithField("value", result.Message).
+				Error("Threshold failed. Stopping run.")
+
+			run.Stop()
+
+			return result
+		}
+	}
+}
+
+func thresholdValue(summary *RequestSummary, metric string) float64 {
+	switch metric {
+	case "min":
+		return summary.Latency.Min
+	case "avg":
+		return summary.Latency.Avg
+	case "p50":
+		return summary.Latency.P50
+	case "p90":
+		return summary.Latency.P90
+	case "p95":
+		return summary.Latency.P95
+	case "p99":
+		return summary.Latency.P99
+	case "max":
+		return summary.Latency.Max
+	case "error_rate":
+		if summary.Count == 0 {
+			return 0
+		}
+
+		failed := summary.Statuses["error"] + summary.Statuses["timeout"] + summary.Statuses["5xx"]
+
+		return float64(failed) / float64(summary.Count)
+	case "expectation_pass_rate":
+		total := summary.Expectations.Passed + summary.Expectations.Failed
+
+		if total == 0 {
+			return 1
+		}
+
+		return float64(summary.Expectations.Passed) / float64(total)
+	}
+
+	return float64(summary.Count)
+}
+
+func formatThresholdValue(metric string, value float64) string {
+	switch metric {
+	case "error_rate", "expectation_pass_rate":
+		return fmt.Sprintf("%.2f%%", value*100)
+	case "requests":
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return formatSeconds(value)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
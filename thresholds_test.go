@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThresholdParse(t *testing.T) {
+	tests := []struct {
+		check    string
+		metric   string
+		operator string
+		value    float64
+	}{
+		{"p95 < 300ms", "p95", "<", 0.3},
+		{"error_rate <= 1%", "error_rate", "<=", 0.01},
+		{"expectation_pass_rate >= 0.99", "expectation_pass_rate", ">=", 0.99},
+		{"requests > 100", "requests", ">", 100},
+	}
+
+	for _, test := range tests {
+		metric, operator, value, err := (&Threshold{Check: test.check}).Parse()
+
+		if err != nil {
+			t.Errorf("Could not parse %q: %s", test.check, err)
+			continue
+		}
+
+		if metric != test.metric || operator != test.operator || value != test.value {
+			t.Errorf("Parsed %q as %s %s %v", test.check, metric, operator, value)
+		}
+	}
+
+	for _, check := range []string{"p95", "p95 = 300ms", "p42 < 300ms", "p95 < fast", "error_rate < some"} {
+		if _, _, _, err := (&Threshold{Check: check}).Parse(); err == nil {
+			t.Errorf("Parsed invalid check %q", check)
+		}
+	}
+}
+
+func TestThresholdEvaluate(t *testing.T) {
+	summary := NewSummary()
+
+	for i := 1; i <= 100; i++ {
+		summary.Handle(&Result{
+			Scenario: "browse",
+			Name:     "start",
+			Status:   "2xx",
+			Latency:  float64(i) / 1000,
+			Expected: true,
+		})
+	}
+
+	summary.Handle(&Result{Scenario: "browse", Name: "search", Status: "5xx", Latency: 0.2})
+	summary.Handle(&Result{Scenario: "browse", Name: "search", Status: "error", Error: "Failed"})
+
+	tests := []struct {
+		threshold Threshold
+		passed    bool
+		message   string
+	}{
+		{Threshold{Request: "start", Check: "p95 < 100ms"}, true, "p95 = 95ms"},
+		{Threshold{Request: "start", Check: "max < 100ms"}, false, "max = 100ms"},
+		{Threshold{Check: "error_rate < 5%"}, true, "error_rate = 1.96%"},
+		{Threshold{Request: "search", Check: "error_rate < 5%"}, false, "error_rate = 100.00%"},
+		{Threshold{Scenario: "browse", Check: "expectation_pass_rate >= 100%"}, true, "expectation_pass_rate = 100.00%"},
+		{Threshold{Check: "requests >= 102"}, true, "requests = 102"},
+		{Threshold{Request: "checkout", Check: "p95 < 1s"}, false, thresholdNoResults},
+	}
+
+	for _, test := range tests {
+		result := test.threshold.Evaluate(summary)
+
+		if result.Passed != test.passed || result.Message != test.message {
+			t.Errorf("Threshold %s evaluated to %s", &test.threshold, result)
+		}
+	}
+}
+
+func TestWatchThresholds(t *testing.T) {
+	defer func(interval time.Duration) {
+		ThresholdInterval = interval
+	}(ThresholdInterval)
+
+	ThresholdInterval = 10 * time.Millisecond
+
+	targets := NewTargets("")
+	targets.Set(&Config{
+		Scenarios: []*Scenario{{Name: "default"}},
+		Thresholds: []*Threshold{
+			{Check: "p99 < 1s"},
+			{Check: "error_rate < 10%", Abort: true},
+		},
+	})
+
+	summary := NewSummary()
+	run := NewRun()
+	run.Go(func() { <-run.Stopping() })
+
+	aborted := make(chan *ThresholdResult, 1)
+
+	go func() {
+		aborted <- WatchThresholds(run, targets, summary)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	summary.Handle(&Result{Scenario: "default", Name: "start", Status: "2xx", Latency: 2})
+
+	if run.Wait(50 * time.Millisecond) {
+		t.Fatal("Run was stopped by a threshold that does not abort")
+	}
+
+	summary.Handle(&Result{Scenario: "default", Name: "start", Status: "error", Error: "Failed"})
+
+	if !run.Wait(time.Second) {
+		t.Fatal("Run was not stopped by a failing threshold")
+	}
+
+	if result := <-aborted; result == nil || result.Threshold.Check != "error_rate < 10%" {
+		t.Errorf("Failing threshold was not returned: %v", result)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// TimelineLatencySamples is the number of latencies kept for every
+	// request and interval to compute percentiles from
+	TimelineLatencySamples = 1000
+
+	// TimelineBuckets is the most intervals kept for every request. Once a
+	// run outlasts them, the interval is doubled and buckets are merged in
+	// pairs, so memory doesn't grow with the length of the run.
+	TimelineBuckets = 2 * ReportPoints
+)
+
+// TimelineLatencyBuckets are the upper bounds in seconds of the latency
+// histograms of the timeline
+var TimelineLatencyBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// Timeline aggregates the results of a run, per request and interval, to
+// show how they changed over the run. The interval widens as the run gets
+// longer.
+type Timeline struct {
+	Started  time.Time
+	Interval time.Duration
+	Requests map[string]*RequestTimeline
+	mutex    sync.Mutex
+}
+
+func NewTimeline(interval time.Duration) *Timeline {
+	return &Timeline{
+		Started:  time.Now(),
+		Interval: interval,
+		Requests: make(map[string]*RequestTimeline),
+	}
+}
+
+func (t *Timeline) Handle(result *Result) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := result.Scenario + "\x00" + result.Name
+	request, ok := t.Requests[key]
+
+	if !ok {
+		request = &RequestTimeline{
+			Scenario:  result.Scenario,
+			Name:      result.Name,
+			Histogram: make([]int, len(TimelineLatencyBuckets)+1),
+		}
+		t.Requests[key] = request
+	}
+
+	i := t.index(result.Time)
+
+	for i >= TimelineBuckets {
+		t.widen()
+		i = t.index(result.Time)
+	}
+
+	request.Add(i, result)
+}
+
+// index returns the interval of a point in time
+func (t *Timeline) index(at time.Time) int {
+	if !at.After(t.Started) {
+		return 0
+	}
+
+	return int(at.Sub(t.Started) / t.Interval)
+}
+
+// widen doubles the interval, and merges the buckets of every request in
+// pairs
+func (t *Timeline) widen() {
+	t.Interval *= 2
+
+	for _, request := range t.Requests {
+		request.Buckets = request.Merged(2)
+	}
+}
+
+// Sorted returns the timelines of every request, sorted by scenario and name
+func (t *Timeline) Sorted() []*RequestTimeline {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	requests := make([]*RequestTimeline, 0, len(t.Requests))
+
+	for _, request := range t.Requests {
+		requests = append(requests, request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+
+		if a.Scenario != b.Scenario {
+			return a.Scenario < b.Scenario
+		}
+
+		return a.Name < b.Name
+	})
+
+	return requests
+}
+
+// RequestTimeline is the results of a request per interval, and a histogram
+// of its latencies with a count per TimelineLatencyBuckets, and one more for
+// the slower ones
+type RequestTimeline struct {
+	Scenario  string
+	Name      string
+	Buckets   []*TimelineBucket
+	Histogram []int
+}
+
+func (r *RequestTimeline) Add(i int, result *Result) {
+	for len(r.Buckets) <= i {
+		r.Buckets = append(r.Buckets, NewTimelineBucket())
+	}
+
+	r.Buckets[i].Add(result)
+
+	if result.Error == "" {
+		r.Histogram[sort.SearchFloat64s(TimelineLatencyBuckets, result.Latency)]++
+	}
+}
+
+// Merged returns the buckets merged size at a time
+func (r *RequestTimeline) Merged(size int) []*TimelineBucket {
+	var merged []*TimelineBucket
+
+	for i, bucket := range r.Buckets {
+		if i%size == 0 {
+			merged = append(merged, NewTimelineBucket())
+		}
+
+		merged[len(merged)-1].Merge(bucket)
+	}
+
+	return merged
+}
+
+// TimelineBucket is the results of a request during an interval
+type TimelineBucket struct {
+	Count     int
+	Statuses  map[string]int
+	latencies Latencies
+}
+
+func NewTimelineBucket() *TimelineBucket {
+	return &TimelineBucket{
+		Statuses:  make(map[string]int),
+		latencies: Latencies{Size: TimelineLatencySamples},
+	}
+}
+
+func (b *TimelineBucket) Add(result *Result) {
+	b.Count++
+	b.Statuses[result.Status]++
+
+	if result.Error == "" {
+		b.latencies.Add(result.Latency)
+	}
+}
+
+func (b *TimelineBucket) Merge(other *TimelineBucket) {
+	b.Count += other.Count
+
+	for status, count := range other.Statuses {
+		b.Statuses[status] += count
+	}
+
+	b.latencies.Merge(&other.latencies)
+}
+
+func (b *TimelineBucket) Latency() LatencySummary {
+	return b.latencies.Summarize()
+}
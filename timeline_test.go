@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	timeline := NewTimeline(time.Second)
+	then := timeline.Started
+
+	for i := 0; i < 10; i++ {
+		timeline.Handle(&Result{
+			Time:     then.Add(time.Duration(i) * 500 * time.Millisecond),
+			Scenario: "browse",
+			Name:     "start",
+			Status:   "2xx",
+			Latency:  float64(i+1) / 100,
+		})
+	}
+
+	timeline.Handle(&Result{Time: then, Scenario: "browse", Name: "start", Status: "error", Error: "Failed"})
+	timeline.Handle(&Result{Time: then.Add(-time.Second), Scenario: "browse", Name: "search", Status: "2xx", Latency: 90})
+
+	requests := timeline.Sorted()
+
+	if len(requests) != 2 || requests[0].Name != "search" {
+		t.Fatalf("Timelines were not sorted: %+v", requests)
+	}
+
+	start := requests[1]
+
+	if len(start.Buckets) != 5 || start.Buckets[0].Count != 3 || start.Buckets[0].Statuses["error"] != 1 {
+		t.Fatalf("Results were not put in buckets per interval: %+v", start.Buckets[0])
+	}
+
+	if latency := start.Buckets[4].Latency(); latency.Min != 0.09 || latency.Max != 0.1 {
+		t.Errorf("Latencies of the bucket were not kept: %+v", latency)
+	}
+
+	// 10ms up to 10ms, 20ms up to 25ms, 30ms to 50ms up to 50ms and the
+	// rest up to 100ms
+	if start.Histogram[3] != 1 || start.Histogram[4] != 1 || start.Histogram[5] != 3 || start.Histogram[6] != 5 {
+		t.Errorf("Latencies were not counted in the histogram: %v", start.Histogram)
+	}
+
+	if search := requests[0]; search.Histogram[len(TimelineLatencyBuckets)] != 1 {
+		t.Errorf("Slower latencies were not counted in the last bucket: %v", search.Histogram)
+	}
+
+	merged := start.Merged(2)
+
+	if len(merged) != 3 || merged[0].Count != 5 || merged[2].Count != 2 {
+		t.Errorf("Buckets were not merged: %d", len(merged))
+	}
+}
+
+func TestTimelineWiden(t *testing.T) {
+	timeline := NewTimeline(time.Second)
+	then := timeline.Started
+
+	for i := 0; i < 3*TimelineBuckets; i++ {
+		timeline.Handle(&Result{
+			Time:     then.Add(time.Duration(i) * time.Second),
+			Scenario: "browse",
+			Name:     "start",
+			Status:   "2xx",
+			Latency:  0.1,
+		})
+	}
+
+	start := timeline.Sorted()[0]
+
+	if timeline.Interval != 4*time.Second || len(start.Buckets) > TimelineBuckets {
+		t.Fatalf("Timeline was not widened: interval %s, %d buckets", timeline.Interval, len(start.Buckets))
+	}
+
+	count := 0
+
+	for _, bucket := range start.Buckets {
+		count += bucket.Count
+	}
+
+	if count != 3*TimelineBuckets || start.Buckets[0].Count != 4 {
+		t.Errorf("Results were lost when widening: %d, %d in the first bucket", count, start.Buckets[0].Count)
+	}
+}
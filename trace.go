@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timings are the phases of a request in seconds. DNS, connect and TLS are 0
+// when a connection was reused. TTFB is from getting a connection until the
+// first byte of the response, and transfer from then until the body has
+// been read.
+type Timings struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	TTFB     float64 `json:"ttfb"`
+	Transfer float64 `json:"transfer"`
+	Reused   bool    `json:"conn_reused"`
+}
+
+// Observe exports the phases of a request, and whether its connection was
+// reused. DNS, connect and TLS are only exported when they happened.
+func (t *Timings) Observe(scenario, name string) {
+	RequestPhaseHistogram.WithLabelValues(scenario, name, "ttfb").Observe(t.TTFB)
+	RequestPhaseHistogram.WithLabelValues(scenario, name, "transfer").Observe(t.Transfer)
+
+	for phase, seconds := range map[string]float64{"dns": t.DNS, "connect": t.Connect, "tls": t.TLS} {
+		if seconds > 0 {
+			RequestPhaseHistogram.WithLabelValues(scenario, name, phase).Observe(seconds)
+		}
+	}
+
+	RequestConnectionsCounter.WithLabelValues(scenario, name, strconv.FormatBool(t.Reused)).Inc()
+}
+
+// tracer measures the phases of a request with httptrace
+type tracer struct {
+	mutex        sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	firstByte    time.Time
+	timings      Timings
+}
+
+// Trace returns req with a context tracing it
+func (t *tracer) Trace(req *http.Request) *http.Request {
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.start(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.done(&t.dnsStart, &t.timings.DNS)
+		},
+		ConnectStart: func(string, string) {
+			t.start(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.done(&t.connectStart, &t.timings.Connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.start(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.done(&t.tlsStart, &t.timings.TLS)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+
+			t.gotConn = time.Now()
+			t.timings.Reused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+
+			t.firstByte = time.Now()
+			t.timings.TTFB = t.firstByte.Sub(t.gotConn).Seconds()
+		},
+	}))
+}
+
+// Read returns the timings once the body has been read
+func (t *tracer) Read() Timings {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.firstByte.IsZero() {
+		t.timings.Transfer = time.Since(t.firstByte).Seconds()
+	}
+
+	return t.timings
+}
+
+// start keeps the time a phase started. Dialing several addresses at once
+// starts a phase more than once, and the first start is kept.
+func (t *tracer) start(started *time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if started.IsZero() {
+		*started = time.Now()
+	}
+}
+
+// done sets the duration of a phase the first time it's done
+func (t *tracer) done(started *time.Time, seconds *float64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if *seconds == 0 && !started.IsZero() {
+		*seconds = time.Since(*started).Seconds()
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		res.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	request := Request{
+		Scenario:   "traced",
+		Name:       "start",
+		URL:        server.URL,
+		Method:     "GET",
+		Parser:     NewHistory(),
+		HTTPClient: server.Client(),
+	}
+
+	reused := RequestConnectionsCounter.WithLabelValues("traced", "start", "true")
+	before := counterValue(reused)
+
+	first, err := request.Send(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timings := first.Timings
+
+	if timings == nil || timings.Reused || timings.Connect <= 0 || timings.TLS <= 0 {
+		t.Fatalf("Connection of the first request was not traced: %+v", timings)
+	}
+
+	if timings.TTFB < 0.02 || timings.TTFB > first.Latency {
+		t.Errorf("Time to first byte did not include the server processing: %+v", timings)
+	}
+
+	second, err := request.Send(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if timings := second.Timings; !timings.Reused || timings.Connect != 0 || timings.TLS != 0 {
+		t.Errorf("Second request did not reuse the connection: %+v", timings)
+	}
+
+	if counterValue(reused) != before+1 {
+		t.Error("Reused connection was not counted")
+	}
+}
@@ -0,0 +1,656 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ValidationError struct {
+	Filename string
+	Line     int
+	Request  string
+	Message  string
+}
+
+func (e *ValidationError) Error() string {
+	location := e.Filename
+
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, e.Line)
+	}
+
+	if e.Request != "" {
+		return fmt.Sprintf("%s: request %q: %s", location, e.Request, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+var yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line ([0-9]+): (.*)$`)
+
+func ValidateTargets(filename string, data []byte) (*Config, ValidationErrors) {
+	v := validator{
+		filename: filename,
+		lines:    strings.Split(string(data), "\n"),
+	}
+
+	var config Config
+
+	err := yaml.UnmarshalStrict(data, &config)
+
+	if err != nil {
+		v.yamlError(err)
+
+		// Unknown keys are reported above, but the rest can still be checked
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return nil, v.errors
+		}
+
+		config = Config{}
+
+		if yaml.Unmarshal(data, &config) != nil {
+			return nil, v.errors
+		}
+	}
+
+	dir := filepath.Dir(filename)
+
+	config.Init()
+	v.config(&config, dir)
+
+	if len(v.errors) == 0 {
+		err = config.LoadClients(dir)
+
+		if err != nil {
+			v.add(0, "", "%s", err)
+		}
+	}
+
+	return &config, v.errors
+}
+
+type validator struct {
+	filename string
+	lines    []string
+	line     int
+	feeders  map[string]*Feeder
+	errors   ValidationErrors
+}
+
+func (v *validator) add(line int, request, format string, args ...interface{}) {
+	v.errors = append(v.errors, &ValidationError{
+		Filename: v.filename,
+		Line:     line,
+		Request:  request,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) config(c *Config, dir string) {
+	if len(c.Scenarios) == 0 {
+		v.add(0, "", "No scenarios defined")
+	}
+
+	v.feeders = c.Feeders
+
+	if c.Timeout < 0 {
+		v.add(v.find(0, "timeout:"), "", "Timeout can't be negative")
+	}
+
+	if c.Metrics != nil {
+		err := c.Metrics.Validate()
+
+		if err != nil {
+			v.add(v.find(0, "metrics:"), "", "%s", err)
+		}
+	}
+
+	if c.Client != nil {
+		_, err := c.Client.Build(dir)
+
+		if err != nil {
+			v.add(v.find(0, "client:"), "", "%s", err)
+		}
+	}
+
+	for _, name := range sortedFeederNames(c.Feeders) {
+		f := c.Feeders[name]
+
+		if f == nil {
+			v.add(v.find(0, name+":"), "", "Feeder %q is empty", name)
+			continue
+		}
+
+		err := f.Validate()
+
+		if err == nil {
+			err = f.Load(dir)
+		}
+
+		if err != nil {
+			v.add(v.find(0, name+":"), "", "%s", err)
+		}
+	}
+
+	defined := make(map[string]bool)
+
+	for i, s := range c.Scenarios {
+		if s == nil {
+			v.add(0, "", "Scenario %d is empty", i)
+			continue
+		}
+
+		if s.Name != DefaultScenarioName || len(c.Scenarios) > 1 {
+			v.line = v.find(v.line+1, "name: "+s.Name)
+		}
+
+		v.scenario(i, s, defined[s.Name], c.Client, dir)
+		defined[s.Name] = true
+	}
+
+	for i, t := range c.Thresholds {
+		v.threshold(i, t, c)
+	}
+}
+
+func (v *validator) threshold(i int, t *Threshold, c *Config) {
+	if t == nil {
+		v.add(0, "", "Threshold %d is empty", i)
+		return
+	}
+
+	line := v.find(0, "check: "+t.Check)
+	err := t.Validate()
+
+	if err != nil {
+		v.add(line, "", "%s", err)
+	}
+
+	if t.Scenario != "" && c.Scenario(t.Scenario) == nil {
+		v.add(line, "", "Threshold refers to scenario %q, which is not defined", t.Scenario)
+	}
+
+	if t.Request == "" {
+		return
+	}
+
+	for _, s := range c.Scenarios {
+		if s == nil || t.Scenario != "" && s.Name != t.Scenario {
+			continue
+		}
+
+		for _, r := range s.Requests {
+			if r != nil && r.Name == t.Request {
+				return
+			}
+		}
+	}
+
+	v.add(line, "", "Threshold refers to request %q, which is not defined", t.Request)
+}
+
+func (v *validator) scenario(
+	i int,
+	s *Scenario,
+	duplicate bool,
+	client *ClientConfig,
+	dir string,
+) {
+	line := v.line
+
+	if s.Name == "" {
+		v.add(line, "", "Scenario %d is missing name", i)
+	}
+
+	if duplicate {
+		v.add(line, "", "Duplicate scenario name %q", s.Name)
+	}
+
+	if s.Concurrency < 1 {
+		v.add(line, "", "Scenario %q: concurrency must be at least 1", s.Name)
+	}
+
+	if s.Sleep < 0 {
+		v.add(line, "", "Scenario %q: sleep can't be negative", s.Name)
+	}
+
+	if s.Repeat < -1 {
+		v.add(line, "", "Scenario %q: repeat must be -1 or more", s.Name)
+	}
+
+	switch s.Session {
+	case SessionIteration, SessionWorker, SessionNone:
+	default:
+		v.add(line, "", "Scenario %q: session must be iteration, worker or none", s.Name)
+	}
+
+	if s.Rate != "" {
+		_, err := ParseRate(s.Rate)
+
+		if err != nil {
+			v.add(line, "", "Scenario %q: %s", s.Name, err)
+		}
+	}
+
+	if s.Rate != "" && len(s.Stages) > 0 {
+		v.add(line, "", "Scenario %q: can't have both rate and stages", s.Name)
+	}
+
+	for i, stage := range s.Stages {
+		if stage == nil {
+			v.add(line, "", "Scenario %q: stage %d is empty", s.Name, i)
+			continue
+		}
+
+		err := stage.Validate()
+
+		if err != nil {
+			v.add(line, "", "Scenario %q: stage %d: %s", s.Name, i, err)
+		}
+	}
+
+	switch s.Arrival {
+	case "":
+	case ArrivalConstant, ArrivalPoisson:
+		if s.Rate == "" {
+			v.add(line, "", "Scenario %q: arrival requires a rate", s.Name)
+		}
+	default:
+		v.add(line, "", "Scenario %q: arrival must be constant or poisson", s.Name)
+	}
+
+	if s.ThinkTime != nil {
+		err := s.ThinkTime.Validate()
+
+		if err != nil {
+			v.add(line, "", "Scenario %q: %s", s.Name, err)
+		}
+	}
+
+	if len(s.Requests) == 0 {
+		v.add(line, "", "Scenario %q: no requests defined", s.Name)
+	}
+
+	v.requests(s, client, dir)
+}
+
+func (v *validator) yamlError(err error) {
+	messages := []string{err.Error()}
+
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		match := yamlLineRe.FindStringSubmatch(message)
+
+		if match == nil {
+			v.add(0, "", "%s", message)
+			continue
+		}
+
+		line, _ := strconv.Atoi(match[1])
+		v.add(line, "", "%s", match[2])
+	}
+}
+
+// find returns the line number of the first line, from the given line and
+// onwards, containing the first line of value. It falls back to from when
+// the value can't be found.
+func (v *validator) find(from int, value string) int {
+	needle := strings.TrimSpace(strings.SplitN(strings.TrimSpace(value), "\n", 2)[0])
+
+	if needle == "" {
+		return from
+	}
+
+	start := from - 1
+
+	if start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(v.lines); i++ {
+		if strings.Contains(v.lines[i], needle) {
+			return i + 1
+		}
+	}
+
+	return from
+}
+
+func (v *validator) requests(s *Scenario, client *ClientConfig, dir string) {
+	defined := make(map[string]bool)
+	line := v.line
+
+	for i, r := range s.Requests {
+		if r == nil {
+			v.add(0, "", "Request %d is empty", i)
+			continue
+		}
+
+		name := r.Name
+
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+			line = v.find(line+1, "url: "+r.URL)
+			v.add(line, name, "Missing name")
+		} else {
+			line = v.find(line+1, "name: "+r.Name)
+		}
+
+		if defined[r.Name] {
+			v.add(line, name, "Duplicate name")
+		}
+
+		if r.URL == "" {
+			v.add(line, name, "Missing url")
+		}
+
+		if r.Timeout < 0 {
+			v.add(line, name, "Timeout can't be negative")
+		}
+
+		switch r.OnFailure {
+		case "", OnFailureContinue, OnFailureSkipIteration, OnFailureAbortWorker, OnFailureAbortRun:
+		default:
+			v.add(line, name, "on_failure must be continue, skip_iteration, abort_worker or abort_run")
+		}
+
+		if r.Retry != nil {
+			err := r.Retry.Validate()
+
+			if err != nil {
+				v.add(line, name, "%s", err)
+			}
+		}
+
+		// Think times inherited from the scenario are validated with it
+		if r.ThinkTime != nil && r.ThinkTime != s.ThinkTime {
+			err := r.ThinkTime.Validate()
+
+			if err != nil {
+				v.add(line, name, "%s", err)
+			}
+		}
+
+		if r.Client != nil {
+			_, err := client.Merge(r.Client).Build(dir)
+
+			if err != nil {
+				v.add(line, name, "%s", err)
+			}
+		}
+
+		v.template(line, name, "url", r.URL, defined)
+		v.template(line, name, "body", r.Body, defined)
+		v.template(line, name, "when", r.When, defined)
+
+		if r.Foreach != nil {
+			v.template(line, name, "foreach.items", r.Foreach.Items, defined)
+
+			if r.Foreach.Items == "" {
+				v.add(line, name, "foreach is missing items")
+			}
+
+			if r.Foreach.Max < 0 {
+				v.add(line, name, "foreach max can't be negative")
+			}
+		}
+
+		for _, k := range sortedKeys(r.Params) {
+			v.template(line, name, "params", k, defined)
+			v.template(line, name, "params."+k, r.Params[k], defined)
+		}
+
+		for _, k := range sortedKeys(r.Headers) {
+			v.template(line, name, "headers", k, defined)
+			v.template(line, name, "headers."+k, r.Headers[k], defined)
+		}
+
+		v.regexp(line, name, "expect.status_code_re", r.Expect.StatusCode)
+		v.regexp(line, name, "expect.body_re", r.Expect.Body)
+
+		for _, k := range sortedKeys(r.Expect.Headers) {
+			v.regexp(line, name, "expect.headers_re."+k, r.Expect.Headers[k])
+		}
+
+		for _, k := range sortedKeys(r.Expect.Cookies) {
+			v.regexp(line, name, "expect.cookies_re."+k, r.Expect.Cookies[k])
+		}
+
+		if r.Expect.Schema != nil {
+			err := r.Expect.Schema.Load(dir)
+
+			if err != nil {
+				v.add(v.find(line, "json_schema:"), name, "expect.json_schema: %s", err)
+			}
+		}
+
+		for i, a := range r.Expect.JSON {
+			if a == nil {
+				v.add(line, name, "expect.json %d is empty", i)
+				continue
+			}
+
+			err := a.Validate()
+
+			if err != nil {
+				v.add(v.find(line, "path: "+a.Path), name, "expect.json: %s", err)
+			}
+		}
+
+		defined[r.Name] = true
+	}
+
+	v.line = line
+}
+
+func (v *validator) regexp(line int, request, field, exp string) {
+	if exp == "" {
+		return
+	}
+
+	_, err := regexp.Compile(exp)
+
+	if err != nil {
+		v.add(v.find(line, exp), request, "%s: %s", field, err)
+	}
+}
+
+func (v *validator) template(line int, request, field, input string, defined map[string]bool) {
+	if input == "" {
+		return
+	}
+
+	tmpl, err := template.
+		New(field).
+		Funcs(NewHistory().Funcs()).
+		Parse(input)
+
+	if err != nil {
+		v.add(v.find(line, input), request, "%s: %s", field, err)
+		return
+	}
+
+	templateCalls(tmpl.Tree.Root, "fromJson", func(args []parse.Node) {
+		if len(args) == 0 {
+			return
+		}
+
+		entry, ok := args[0].(*parse.StringNode)
+
+		if !ok || defined[entry.Text] {
+			return
+		}
+
+		v.add(
+			v.find(line, entry.Quoted),
+			request,
+			"%s: fromJson refers to %q, which is not defined before this request",
+			field,
+			entry.Text,
+		)
+	})
+
+	templateCalls(tmpl.Tree.Root, "feed", func(args []parse.Node) {
+		if len(args) == 0 {
+			return
+		}
+
+		feeder, ok := args[0].(*parse.StringNode)
+
+		if !ok {
+			return
+		}
+
+		if _, ok := v.feeders[feeder.Text]; ok {
+			return
+		}
+
+		v.add(
+			v.find(line, feeder.Quoted),
+			request,
+			"%s: feed refers to %q, which is not a defined feeder",
+			field,
+			feeder.Text,
+		)
+	})
+}
+
+// templateCalls walks a parsed template and calls visit with the arguments
+// of every call to the function named fn.
+func templateCalls(node parse.Node, fn string, visit func(args []parse.Node)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+
+		for _, child := range n.Nodes {
+			templateCalls(child, fn, visit)
+		}
+	case *parse.ActionNode:
+		templateCalls(n.Pipe, fn, visit)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+
+		for _, cmd := range n.Cmds {
+			templateCalls(cmd, fn, visit)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == fn {
+				visit(n.Args[1:])
+			}
+		}
+
+		for _, arg := range n.Args {
+			templateCalls(arg, fn, visit)
+		}
+	case *parse.IfNode:
+		templateCalls(n.Pipe, fn, visit)
+		templateCalls(n.List, fn, visit)
+		templateCalls(n.ElseList, fn, visit)
+	case *parse.RangeNode:
+		templateCalls(n.Pipe, fn, visit)
+		templateCalls(n.List, fn, visit)
+		templateCalls(n.ElseList, fn, visit)
+	case *parse.WithNode:
+		templateCalls(n.Pipe, fn, visit)
+		templateCalls(n.List, fn, visit)
+		templateCalls(n.ElseList, fn, visit)
+	}
+}
+
+func sortedFeederNames(feeders map[string]*Feeder) []string {
+	names := make([]string, 0, len(feeders))
+
+	for name := range feeders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func Validate(args []string) int {
+	var targets string
+
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.StringVar(&targets, "targets", "", "Targets path")
+	flags.Parse(args)
+
+	filenames := flags.Args()
+
+	if targets != "" {
+		filenames = append([]string{targets}, filenames...)
+	}
+
+	if len(filenames) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: goload validate [-targets] <targets file> ...")
+		return 2
+	}
+
+	code := 0
+
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+
+		_, errs := ValidateTargets(filename, data)
+
+		if len(errs) > 0 {
+			fmt.Fprintln(os.Stderr, errs.Error())
+			code = 1
+			continue
+		}
+
+		fmt.Printf("%s: OK\n", filename)
+	}
+
+	return code
+}
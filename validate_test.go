@@ -0,0 +1,331 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTargets(t *testing.T) {
+	content := []byte(`
+- name: login
+  url: http://some-host/login
+  method: POST
+  expect:
+    status_code_re: '2[0-9]{2}'
+- name: profile
+  url: 'http://some-host/profile/{{ fromJson "login" "user.id" }}'
+  method: GET
+`)
+
+	config, errs := ValidateTargets("targets.yml", content)
+
+	if len(errs) > 0 {
+		t.Fatalf("Valid targets returned errors: %s", errs)
+	}
+
+	if len(config.Scenarios) != 1 || len(config.Scenarios[0].Requests) != 2 {
+		t.Error("Expected a default scenario with 2 requests")
+	}
+}
+
+func TestValidateTargetsErrors(t *testing.T) {
+	content := []byte(`
+- name: login
+  url: 'http://some-host/{{ fromJson "profile" "id" }}'
+  methd: POST
+  expect:
+    status_code_re: '2[0-9'
+- name: profile
+  url: 'http://some-host/profile/{{ fromJson "login" "user.id" }'
+  headers:
+    Authorization: '{{ nope }}'
+- name: profile
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:4: field methd not found in type main.Request`,
+		`targets.yml:3: request "login": url: fromJson refers to "profile", which is not defined before this request`,
+		`targets.yml:6: request "login": expect.status_code_re: error parsing regexp`,
+		`targets.yml:8: request "profile": url: template: url:1: unexpected "}" in operand`,
+		`targets.yml:10: request "profile": headers.Authorization: template: headers.Authorization:1: function "nope" not defined`,
+		`targets.yml:11: request "profile": Duplicate name`,
+		`targets.yml:11: request "profile": Missing url`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if !strings.HasPrefix(errs[i].Error(), e) {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateScenarios(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: browse
+    concurrency: 0
+    requests:
+      - name: start
+        url: http://some-host/
+  - name: browse
+    requests:
+      - name: login
+        url: 'http://some-host/{{ fromJson "start" "id" }}'
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:3: Scenario "browse": concurrency must be at least 1`,
+		`targets.yml:8: Duplicate scenario name "browse"`,
+		`targets.yml:11: request "login": url: fromJson refers to "start", which is not defined before this request`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateTargetsSyntaxError(t *testing.T) {
+	_, errs := ValidateTargets("targets.yml", []byte("- name: [broken"))
+
+	if len(errs) != 1 || errs[0].Line != 1 {
+		t.Errorf("Syntax error was not reported with line: %s", errs)
+	}
+}
+
+func TestValidateThinkTime(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: browse
+    think_time:
+      distribution: normal
+    requests:
+      - name: start
+        url: http://some-host/
+      - name: search
+        url: http://some-host/search
+        think_time:
+          distribution: uniform
+          min: 3s
+          max: 1s
+`)
+
+	config, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:3: Scenario "browse": Think time with normal distribution must set mean`,
+		`targets.yml:9: request "search": Think time max can't be less than min`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+
+	requests := config.Scenarios[0].Requests
+
+	if requests[0].ThinkTime != config.Scenarios[0].ThinkTime || requests[1].ThinkTime.Distribution != ThinkTimeUniform {
+		t.Error("Requests did not inherit the think time of their scenario")
+	}
+}
+
+func TestValidateRate(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: open
+    rate: 200/week
+    arrival: burst
+    requests:
+      - name: start
+        url: http://some-host/
+  - name: closed
+    arrival: poisson
+    requests:
+      - name: start
+        url: http://some-host/
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:3: Scenario "open": Rate must be per s, m, h or a duration, not "200/week"`,
+		`targets.yml:3: Scenario "open": arrival must be constant or poisson`,
+		`targets.yml:9: Scenario "closed": arrival requires a rate`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateStages(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: ramp
+    rate: 10/s
+    stages:
+      - duration: 2m
+        target: 50
+      - duration: 0s
+        target: -1
+    requests:
+      - name: start
+        url: http://some-host/
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:3: Scenario "ramp": can't have both rate and stages`,
+		`targets.yml:3: Scenario "ramp": stage 1: Stage duration must be positive`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateThresholds(t *testing.T) {
+	content := []byte(`
+scenarios:
+  - name: browse
+    requests:
+      - name: start
+        url: http://some-host/
+thresholds:
+  - request: start
+    check: p95 < 300ms
+  - scenario: checkout
+    check: error_rate < 1%
+  - request: search
+    check: p95 = 1s
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:11: Threshold refers to scenario "checkout", which is not defined`,
+		`targets.yml:13: Threshold operator must be <, <=, > or >=, not "="`,
+		`targets.yml:13: Threshold refers to request "search", which is not defined`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	content := []byte(`
+metrics:
+  buckets: [0.1, 0.5, 0.25]
+scenarios:
+  - name: browse
+    requests:
+      - name: start
+        url: http://some-host/
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := `targets.yml:2: Metrics buckets: Buckets must be increasing, but 0.25 follows 0.5`
+
+	if len(errs) != 1 || errs[0].Error() != expected {
+		t.Errorf("Expected error\n%s\ngot\n%s", expected, errs)
+	}
+}
+
+func TestValidateJSONAssertions(t *testing.T) {
+	content := []byte(`
+- name: start
+  url: http://some-host/
+  expect:
+    json:
+      - path: user.id
+        type: integer
+      - path: items
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:6: request "start": expect.json: JSON assertion user.id type must be string, number, boolean, array, object, null, not "integer"`,
+		`targets.yml:8: request "start": expect.json: JSON assertion items must have a condition`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	content := []byte(`
+- name: start
+  url: http://some-host/
+  expect:
+    json_schema: missing.json
+- name: user
+  url: http://some-host/user
+  expect:
+    json_schema:
+      type: objekt
+`)
+
+	_, errs := ValidateTargets("targets.yml", content)
+
+	expected := []string{
+		`targets.yml:5: request "start": expect.json_schema: open missing.json: no such file or directory`,
+		`targets.yml:9: request "user": expect.json_schema: Schema type at #/type must be null, boolean, object, array, number, integer, string, not objekt`,
+	}
+
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%s", len(expected), len(errs), errs)
+	}
+
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Errorf("Error %d did not match\n%s\n%s", i, errs[i], e)
+		}
+	}
+}
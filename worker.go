@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Worker is a virtual user running the requests of a scenario, with its own
+// history, feeder rows and cookies
+type Worker struct {
+	ID         int
+	Targets    *Targets
+	Name       string
+	Scenario   *Scenario
+	Repeated   int
+	runner     Runner
+	collection RequestCollection
+	history    *History
+	version    int
+}
+
+// workers counts the workers created, to give each one an id
+var workers int64
+
+func NewWorker(targets *Targets, name string, status *Status, results ResultHandler) *Worker {
+	w := &Worker{
+		ID:      int(atomic.AddInt64(&workers, 1)),
+		Targets: targets,
+		Name:    name,
+		history: NewHistory(),
+	}
+
+	w.runner = Runner{
+		History:  w.history,
+		Requests: &w.collection,
+		Status:   status,
+		Results:  results,
+		Worker:   w.ID,
+	}
+
+	return w
+}
+
+// Update picks up changes of the targets. It returns false when the
+// scenario has been removed from them.
+func (w *Worker) Update() bool {
+	config, current := w.Targets.Config()
+
+	if current == w.version {
+		return true
+	}
+
+	scenario := config.Scenario(w.Name)
+
+	if scenario == nil {
+		return false
+	}
+
+	w.Scenario = scenario
+	w.collection.Requests = CloneRequests(scenario.Requests)
+	w.history.Feed = NewFeed(config.Feeders, scenario.Feeders)
+	w.runner.Feed = w.history.Feed
+
+	if w.runner.Session == nil || w.runner.Session.Mode != scenario.Session {
+		w.history.Session = NewSession(scenario.Session)
+		w.runner.Session = w.history.Session
+	}
+
+	w.version = current
+
+	return true
+}
+
+// Work runs through the requests of the scenario over and over, until the
+// run or the worker is stopped, ctx is cancelled or the worker is done. A
+// nil stop never closes.
+func (w *Worker) Work(ctx context.Context, run *Run, stop <-chan struct{}) {
+	ActiveVirtualUsersGauge.WithLabelValues(w.Name).Inc()
+	defer ActiveVirtualUsersGauge.WithLabelValues(w.Name).Dec()
+
+	for {
+		select {
+		case <-run.Stopping():
+			w.Logger().Info("Run stopped. Closing down.")
+			return
+		case <-stop:
+			w.Logger().Info("Worker stopped. Closing down.")
+			return
+		default:
+		}
+
+		if !w.Update() {
+			w.Logger().Warn("Scenario was removed from targets. Closing down.")
+			return
+		}
+
+		scenario := w.Scenario
+		sleep := time.Duration(scenario.Sleep) * time.Second
+		runLogger := w.Logger().WithField("sleep", sleep.String())
+
+		runLogger.Info("Initiated requests")
+		err := w.Iterate(ctx)
+
+		if ctx.Err() != nil {
+			runLogger.Info("Run cancelled. Closing down.")
+			return
+		}
+
+		if errors.Is(err, ErrStopRun) {
+			runLogger.WithError(err).Warn("Stopping run. Closing down.")
+			run.Stop()
+			return
+		}
+
+		if errors.Is(err, ErrStopWorker) {
+			runLogger.WithError(err).Warn("Stopping worker.")
+			return
+		}
+
+		if scenario.Repeat > -1 && w.Repeated >= scenario.Repeat {
+			runLogger.Info("Number of repeats reached. Closing down.")
+			return
+		}
+
+		w.Repeated++
+		runLogger.Info("Requests ended. Sleeping intil next run.")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-run.Stopping():
+			w.Logger().Info("Run stopped. Closing down.")
+			return
+		case <-stop:
+			w.Logger().Info("Worker stopped. Closing down.")
+			return
+		case <-time.After(sleep):
+		}
+	}
+}
+
+// Iterate runs through the requests of the scenario once
+func (w *Worker) Iterate(ctx context.Context) error {
+	IterationsCounter.WithLabelValues(w.Name).Inc()
+	w.runner.Iteration++
+
+	then := time.Now()
+	err := w.runner.Run(ctx)
+
+	IterationDurationHistogram.WithLabelValues(w.Name).Observe(time.Since(then).Seconds())
+
+	return err
+}
+
+func (w *Worker) Logger() *logrus.Entry {
+	logger := logrus.
+		WithField("scenario", w.Name).
+		WithField("worker", w.ID).
+		WithField("requests", len(w.collection.Requests)).
+		WithField("repeated", w.Repeated)
+
+	if w.Scenario != nil {
+		logger = logger.WithField("repeat", w.Scenario.Repeat)
+
+		for k, v := range w.Scenario.Labels {
+			logger = logger.WithField(k, v)
+		}
+	}
+
+	return logger
+}